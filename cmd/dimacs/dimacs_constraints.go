@@ -33,35 +33,23 @@ func (d *ConstraintGenerator) GetVariables(ctx context.Context, entitySource inp
 		return nil, err
 	}
 
-	// create constraints out of the clauses
+	// create constraints out of the clauses, attaching each one to
+	// the variable of its first literal
 	for _, clause := range d.dimacs.clauses {
 		terms := strings.Split(clause, " ")
 		if len(terms) == 0 {
 			continue
 		}
-		first := terms[0]
-
-		if len(terms) == 1 {
-			// TODO: check constraints haven't already been added to the variable
-			variable := varMap[deppy.Identifier(strings.TrimPrefix(first, "-"))]
-			if strings.HasPrefix(first, "-") {
-				variable.AddConstraint(constraint.Not())
+		literals := make([]constraint.ClauseLiteral, len(terms))
+		for i, term := range terms {
+			id := deppy.Identifier(strings.TrimPrefix(term, "-"))
+			if strings.HasPrefix(term, "-") {
+				literals[i] = constraint.Negative(id)
 			} else {
-				// TODO: is this the right constraint here? (given that its an achoring constraint?)
-				variable.AddConstraint(constraint.Mandatory())
+				literals[i] = constraint.Positive(id)
 			}
-			continue
-		}
-		for i := 1; i < len(terms); i++ {
-			variable := varMap[deppy.Identifier(strings.TrimPrefix(first, "-"))]
-			second := terms[i]
-			negSubject := strings.HasPrefix(first, "-")
-			negOperand := strings.HasPrefix(second, "-")
-
-			// TODO: this Or constraint is hacky as hell
-			variable.AddConstraint(constraint.Or(deppy.Identifier(strings.TrimPrefix(second, "-")), negSubject, negOperand))
-			first = second
 		}
+		varMap[literals[0].ID].AddConstraint(constraint.Clause(literals...))
 	}
 
 	return variables, nil
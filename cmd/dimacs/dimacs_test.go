@@ -2,12 +2,18 @@ package dimacs_test
 
 import (
 	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"github.com/operator-framework/deppy/cmd/dimacs"
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/solver"
 )
 
 func TestDimacs(t *testing.T) {
@@ -34,3 +40,46 @@ var _ = Describe("Dimacs", func() {
 		Expect(d.Clauses()).To(Equal([]string{"1 2 3"}))
 	})
 })
+
+var _ = Describe("ConstraintGenerator", func() {
+	solve := func(path string) (*dimacs.Dimacs, *solver.Solution) {
+		f, err := os.Open(path)
+		Expect(err).ToNot(HaveOccurred())
+		defer f.Close()
+		d, err := dimacs.NewDimacs(f)
+		Expect(err).ToNot(HaveOccurred())
+		so, err := solver.NewDeppySolver(dimacs.NewDimacsEntitySource(d), dimacs.NewDimacsVariableSource(d))
+		Expect(err).ToNot(HaveOccurred())
+		solution, err := so.Solve(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		return d, solution
+	}
+
+	It("should find a model for every satisfiable problem in the corpus", func() {
+		paths, err := filepath.Glob("testdata/sat/*.cnf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths).ToNot(BeEmpty())
+		for _, path := range paths {
+			d, solution := solve(path)
+			Expect(solution.Error()).ToNot(HaveOccurred(), path)
+			for _, clause := range d.Clauses() {
+				satisfied := false
+				for _, term := range strings.Split(clause, " ") {
+					selected := solution.IsSelected(deppy.Identifier(strings.TrimPrefix(term, "-")))
+					satisfied = satisfied || selected != strings.HasPrefix(term, "-")
+				}
+				Expect(satisfied).To(BeTrue(), "%s: clause (%s) not satisfied", path, clause)
+			}
+		}
+	})
+
+	It("should report every unsatisfiable problem in the corpus", func() {
+		paths, err := filepath.Glob("testdata/unsat/*.cnf")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(paths).ToNot(BeEmpty())
+		for _, path := range paths {
+			_, solution := solve(path)
+			Expect(solution.Error()).To(HaveOccurred(), path)
+		}
+	})
+})
@@ -0,0 +1,71 @@
+// Package parse holds the parts shared by the lexers of the textual
+// languages of deppy, such as constraint expressions and entity
+// queries: scanning their input and reporting syntax errors.
+package parse
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// SyntaxError describes malformed input. Offset is the byte offset of
+// the offending input, Line and Column its one-based position.
+type SyntaxError struct {
+	Offset int
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at line %d, column %d: %s", e.Line, e.Column, e.Msg)
+}
+
+// Scanner holds the input of a lexer and the offset it has reached.
+type Scanner struct {
+	Input  string
+	Offset int
+}
+
+// SkipSpace advances past white space, and returns true if any input
+// remains.
+func (s *Scanner) SkipSpace() bool {
+	for s.Offset < len(s.Input) {
+		r, size := utf8.DecodeRuneInString(s.Input[s.Offset:])
+		if !unicode.IsSpace(r) {
+			return true
+		}
+		s.Offset += size
+	}
+	return false
+}
+
+// Rest returns the input that remains to be scanned.
+func (s *Scanner) Rest() string {
+	return s.Input[s.Offset:]
+}
+
+// Quoted scans the double-quoted Go string literal at the current
+// offset, and returns it with its quotes.
+func (s *Scanner) Quoted() (string, error) {
+	quoted, err := strconv.QuotedPrefix(s.Rest())
+	if err != nil {
+		return "", s.Errorf(s.Offset, "unterminated or malformed string")
+	}
+	s.Offset += len(quoted)
+	return quoted, nil
+}
+
+// Errorf returns a SyntaxError at the given offset of the input.
+func (s *Scanner) Errorf(offset int, format string, args ...interface{}) *SyntaxError {
+	before := s.Input[:offset]
+	return &SyntaxError{
+		Offset: offset,
+		Line:   strings.Count(before, "\n") + 1,
+		Column: utf8.RuneCountInString(before[strings.LastIndex(before, "\n")+1:]) + 1,
+		Msg:    fmt.Sprintf(format, args...),
+	}
+}
@@ -0,0 +1,37 @@
+package parse
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScannerErrorf(t *testing.T) {
+	s := Scanner{Input: "a &&\n  é $"}
+	err := s.Errorf(10, "unexpected character %q", '$')
+	assert.Equal(t, &SyntaxError{Offset: 10, Line: 2, Column: 5, Msg: `unexpected character '$'`}, err)
+	assert.EqualError(t, err, `syntax error at line 2, column 5: unexpected character '$'`)
+}
+
+func TestScannerSkipSpace(t *testing.T) {
+	s := Scanner{Input: " \n\ta "}
+	assert.True(t, s.SkipSpace())
+	assert.Equal(t, "a ", s.Rest())
+	s.Offset++
+	assert.False(t, s.SkipSpace())
+	assert.Equal(t, 5, s.Offset)
+}
+
+func TestScannerQuoted(t *testing.T) {
+	s := Scanner{Input: `"a \"b\"" c`}
+	quoted, err := s.Quoted()
+	assert.NoError(t, err)
+	assert.Equal(t, `"a \"b\""`, quoted)
+	assert.Equal(t, " c", s.Rest())
+
+	s = Scanner{Input: "x\n\"a"}
+	s.Offset = 2
+	_, err = s.Quoted()
+	assert.Equal(t, &SyntaxError{Offset: 2, Line: 2, Column: 1, Msg: "unterminated or malformed string"}, err)
+	assert.Equal(t, 2, s.Offset)
+}
@@ -2,6 +2,7 @@ package solver
 
 import (
 	"context"
+	"fmt"
 	"math/rand"
 	"strconv"
 	"testing"
@@ -87,3 +88,90 @@ func BenchmarkNewInput(b *testing.B) {
 		}
 	}
 }
+
+// syntheticCatalog returns a solvable problem shaped like a large
+// operator catalog: packages with several bundles each, a
+// per-package uniqueness variable, bundle dependencies on other
+// packages and a handful of mandatory requirements.
+func syntheticCatalog(size int) []deppy.Variable {
+	const (
+		seed              = 9
+		bundlesPerPackage = 8
+		pDependency       = .3
+		nDependency       = 3
+		pRequirement      = .01
+	)
+
+	r := rand.New(rand.NewSource(seed))
+	packages := size / (bundlesPerPackage + 1)
+	bundle := func(p, b int) deppy.Identifier {
+		return deppy.Identifier(fmt.Sprintf("bundle-%d-%d", p, b))
+	}
+	candidates := func(p int) []deppy.Identifier {
+		ids := make([]deppy.Identifier, bundlesPerPackage)
+		for b := range ids {
+			ids[b] = bundle(p, b)
+		}
+		return ids
+	}
+
+	result := make([]deppy.Variable, 0, size)
+	for p := 0; p < packages; p++ {
+		if r.Float64() < pRequirement {
+			result = append(result, variable(deppy.Identifier(fmt.Sprintf("require-%d", p)), constraint.Mandatory(), constraint.Dependency(candidates(p)...)))
+		}
+		for b := 0; b < bundlesPerPackage; b++ {
+			var c []deppy.Constraint
+			if p > 0 && r.Float64() < pDependency {
+				for x := r.Intn(nDependency) + 1; x > 0; x-- {
+					c = append(c, constraint.Dependency(candidates(r.Intn(p))...))
+				}
+			}
+			result = append(result, variable(bundle(p, b), c...))
+		}
+		result = append(result, variable(deppy.Identifier(fmt.Sprintf("uniqueness-%d", p)), constraint.AtMost(1, candidates(p)...)))
+	}
+	return result
+}
+
+func benchmarkSolve(b *testing.B, input []deppy.Variable) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		s, err := NewSolver(WithInput(input))
+		if err != nil {
+			b.Fatalf("failed to initialize solver: %s", err)
+		}
+		_, err = s.Solve(context.Background())
+		if err != nil {
+			b.Fatalf("failed to solve: %s", err)
+		}
+	}
+}
+
+func benchmarkNewInput(b *testing.B, input []deppy.Variable) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := NewSolver(WithInput(input))
+		if err != nil {
+			b.Fatalf("failed to initialize solver: %s", err)
+		}
+	}
+}
+
+func BenchmarkSolve10k(b *testing.B) {
+	benchmarkSolve(b, syntheticCatalog(10000))
+}
+
+func BenchmarkSolve100k(b *testing.B) {
+	benchmarkSolve(b, syntheticCatalog(100000))
+}
+
+func BenchmarkNewInput10k(b *testing.B) {
+	benchmarkNewInput(b, syntheticCatalog(10000))
+}
+
+func BenchmarkNewInput100k(b *testing.B) {
+	benchmarkNewInput(b, syntheticCatalog(100000))
+}
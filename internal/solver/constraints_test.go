@@ -35,6 +35,14 @@ func TestOrder(t *testing.T) {
 			Name:       "conflict",
 			Constraint: constraint.Conflict("a"),
 		},
+		{
+			Name:       "at least",
+			Constraint: constraint.AtLeast(1, "a", "b"),
+		},
+		{
+			Name:       "exactly",
+			Constraint: constraint.Exactly(1, "a", "b"),
+		},
 	} {
 		t.Run(tt.Name, func(t *testing.T) {
 			assert.Equal(t, tt.Expected, tt.Constraint.Order())
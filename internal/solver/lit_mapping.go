@@ -26,11 +26,27 @@ func (inconsistentLitMapping) Error() string {
 // litMapping performs translation between the input and output types of
 // Solve (Constraints, Variables, etc.) and the variables that
 // appear in the SAT formula.
+//
+// Identifiers are interned once, as the position of their Variable in
+// the input. Variable literals are allocated contiguously before any
+// constraint is applied, so the tables indexed by Variable are dense
+// slices addressed by the offset of a literal's variable from the first
+// allocated one, and constraint applications are found through a dense
+// table indexed by literal.
 type litMapping struct {
-	inorder     []deppy.Variable
-	variables   map[z.Lit]deppy.Variable
-	lits        map[deppy.Identifier]z.Lit
-	constraints map[z.Lit]deppy.AppliedConstraint
+	inorder    []deppy.Variable
+	base       z.Var
+	positions  map[deppy.Identifier]int
+	candidates [][][]z.Lit // per-Variable preference lists, one for each non-empty choice of its Constraints
+	// missing holds, by Variable position, the Identifiers listed by
+	// the Order of its constraints that don't identify any Variable.
+	// They are reported once the Variable is guessed.
+	missing     map[int][]deppy.Identifier
+	anchors     []z.Lit
+	applied     []deppy.AppliedConstraint
+	appliedAt   []int32 // 1 + index in applied, by constraint literal
+	assumptions []z.Lit // constraint literals in application order
+	named       map[string]z.Lit
 	c           *logic.C
 	errs        inconsistentLitMapping
 }
@@ -41,26 +57,41 @@ type litMapping struct {
 // inputs to the underlying solver.
 func newLitMapping(variables []deppy.Variable) (*litMapping, error) {
 	d := litMapping{
-		inorder:     variables,
-		variables:   make(map[z.Lit]deppy.Variable, len(variables)),
-		lits:        make(map[deppy.Identifier]z.Lit, len(variables)),
-		constraints: make(map[z.Lit]deppy.AppliedConstraint),
-		c:           logic.NewCCap(len(variables)),
+		inorder:    variables,
+		positions:  make(map[deppy.Identifier]int, len(variables)),
+		candidates: make([][][]z.Lit, len(variables)),
+		c:          logic.NewCCap(len(variables)),
 	}
 
+	// Candidate lists share a single backing array to avoid an
+	// allocation per Constraint.
+	var buffer []z.Lit
+
 	// First pass to assign lits:
-	for _, variable := range variables {
+	for i, variable := range variables {
 		im := d.c.Lit()
-		if _, ok := d.lits[variable.Identifier()]; ok {
+		if i == 0 {
+			d.base = im.Var()
+		}
+		if _, ok := d.positions[variable.Identifier()]; ok {
 			return nil, DuplicateIdentifier(variable.Identifier())
 		}
-		d.lits[variable.Identifier()] = im
-		d.variables[im] = variable
+		d.positions[variable.Identifier()] = i
 	}
 
-	for _, variable := range variables {
+	for i, variable := range variables {
+		anchor := false
 		for _, constraint := range variable.Constraints() {
-			m := constraint.Apply(&d, variable.Identifier())
+			anchor = anchor || constraint.Anchor()
+
+			for _, order := range deppy.ChoicesOf(constraint) {
+				buffer = d.addCandidates(i, order, buffer)
+			}
+
+			m, err := deppy.ApplyWith(&d, constraint, variable.Identifier())
+			if err != nil {
+				return nil, fmt.Errorf("error applying constraint to %q: %w", variable.Identifier(), err)
+			}
 			if m == z.LitNull {
 				// This constraint doesn't have a
 				// useful representation in the SAT
@@ -68,16 +99,93 @@ func newLitMapping(variables []deppy.Variable) (*litMapping, error) {
 				continue
 			}
 
-			d.constraints[m] = deppy.AppliedConstraint{
+			d.setApplied(m, deppy.AppliedConstraint{
 				Variable:   variable,
 				Constraint: constraint,
-			}
+				Metadata:   deppy.MetadataOf(constraint),
+			})
+		}
+		if anchor {
+			d.anchors = append(d.anchors, d.litAt(i))
 		}
 	}
 
 	return &d, nil
 }
 
+// addCandidates adds the literals of the Variables in order as a
+// preference list of the Variable at position i of the input, appending
+// them to buffer, and returns the extended buffer.
+func (d *litMapping) addCandidates(i int, order []deppy.Identifier, buffer []z.Lit) []z.Lit {
+	if len(order) == 0 {
+		return buffer
+	}
+	start := len(buffer)
+	for _, id := range order {
+		m, err := d.Lookup(id)
+		if err != nil {
+			if d.missing == nil {
+				d.missing = map[int][]deppy.Identifier{}
+			}
+			d.missing[i] = append(d.missing[i], id)
+		}
+		buffer = append(buffer, m)
+	}
+	d.candidates[i] = append(d.candidates[i], buffer[start:len(buffer):len(buffer)])
+	return buffer
+}
+
+// setApplied records the constraint application corresponding to the
+// provided literal, replacing any previous one, and assumes the literal
+// the first time it is recorded.
+func (d *litMapping) setApplied(m z.Lit, a deppy.AppliedConstraint) {
+	if n := int(m) + 1; n > len(d.appliedAt) {
+		if n > cap(d.appliedAt) {
+			grown := make([]int32, len(d.appliedAt), 2*n)
+			copy(grown, d.appliedAt)
+			d.appliedAt = grown
+		}
+		d.appliedAt = d.appliedAt[:n]
+	}
+	if j := d.appliedAt[m]; j > 0 {
+		d.applied[j-1] = a
+		return
+	}
+	d.applied = append(d.applied, a)
+	d.appliedAt[m] = int32(len(d.applied))
+	d.assumptions = append(d.assumptions, m)
+}
+
+// appliedOf returns the constraint application corresponding to the
+// provided literal, if any.
+func (d *litMapping) appliedOf(m z.Lit) (deppy.AppliedConstraint, bool) {
+	if int(m) < len(d.appliedAt) {
+		if j := d.appliedAt[m]; j > 0 {
+			return d.applied[j-1], true
+		}
+	}
+	return deppy.AppliedConstraint{}, false
+}
+
+// litAt returns the literal of the Variable at position i of the input.
+func (d *litMapping) litAt(i int) z.Lit {
+	return (d.base + z.Var(i)).Pos()
+}
+
+// indexOf returns the position in the input of the Variable
+// corresponding to the provided literal, or -1 if the literal does not
+// correspond to any Variable.
+func (d *litMapping) indexOf(m z.Lit) int {
+	if m == z.LitNull || !m.IsPos() {
+		return -1
+	}
+	i := int(m.Var()) - int(d.base)
+	if i < 0 || i >= len(d.inorder) {
+		return -1
+	}
+	return i
+}
+
 // LogicCircuit returns the lit mappings internal logic circuit
 // used by constraint for translation into boolean expressions processed by the solver
 func (d *litMapping) LogicCircuit() *logic.C {
@@ -87,20 +195,56 @@ func (d *litMapping) LogicCircuit() *logic.C {
 // LitOf returns the positive literal corresponding to the Variable
 // with the given Identifier.
 func (d *litMapping) LitOf(id deppy.Identifier) z.Lit {
-	m, ok := d.lits[id]
-	if ok {
-		return m
+	if i, ok := d.positions[id]; ok {
+		return d.litAt(i)
 	}
 	d.errs = append(d.errs, fmt.Errorf("variable %q referenced but not provided", id))
 	return z.LitNull
 }
 
+// Lookup returns the positive literal corresponding to the Variable
+// with the given Identifier, or an error if there is no such Variable.
+// Unlike LitOf, it doesn't record the error.
+func (d *litMapping) Lookup(id deppy.Identifier) (z.Lit, error) {
+	i, ok := d.positions[id]
+	if !ok {
+		return z.LitNull, fmt.Errorf("variable %q referenced but not provided", id)
+	}
+	return d.litAt(i), nil
+}
+
+// NewLit returns a fresh auxiliary literal.
+func (d *litMapping) NewLit() z.Lit {
+	return d.c.Lit()
+}
+
+// Named returns the literal built under name, invoking build only the
+// first time it succeeds for that name.
+func (d *litMapping) Named(name string, build func() (z.Lit, error)) (z.Lit, error) {
+	if m, ok := d.named[name]; ok {
+		return m, nil
+	}
+	m, err := build()
+	if err != nil {
+		return z.LitNull, err
+	}
+	if d.named == nil {
+		d.named = make(map[string]z.Lit)
+	}
+	d.named[name] = m
+	return m, nil
+}
+
+// Report records an error reported by a constraint.
+func (d *litMapping) Report(err error) {
+	d.errs = append(d.errs, err)
+}
+
 // VariableOf returns the Variable corresponding to the provided
 // literal, or a zeroVariable if no such Variable exists.
 func (d *litMapping) VariableOf(m z.Lit) deppy.Variable {
-	i, ok := d.variables[m]
-	if ok {
-		return i
+	if i := d.indexOf(m); i >= 0 {
+		return d.inorder[i]
 	}
 	d.errs = append(d.errs, fmt.Errorf("no variable corresponding to %s", m))
 	return zeroVariable{}
@@ -110,7 +254,7 @@ func (d *litMapping) VariableOf(m z.Lit) deppy.Variable {
 // the provided literal, or a zeroConstraint if no such constraint
 // exists.
 func (d *litMapping) ConstraintOf(m z.Lit) deppy.AppliedConstraint {
-	if a, ok := d.constraints[m]; ok {
+	if a, ok := d.appliedOf(m); ok {
 		return a
 	}
 	d.errs = append(d.errs, fmt.Errorf("no constraint corresponding to %s", m))
@@ -142,9 +286,7 @@ func (d *litMapping) AddConstraints(g inter.S) {
 }
 
 func (d *litMapping) AssumeConstraints(s inter.S) {
-	for m := range d.constraints {
-		s.Assume(m)
-	}
+	s.Assume(d.assumptions...)
 }
 
 // CardinalityConstrainer constructs a sorting network to provide
@@ -169,23 +311,41 @@ func (d *litMapping) CardinalityConstrainer(g inter.Adder, ms []z.Lit) *logic.Ca
 // every Variable with at least one "Anchor" constraint, in the
 // Order they appear in the input.
 func (d *litMapping) AnchorIdentifiers() []deppy.Identifier {
-	var ids []deppy.Identifier
-	for _, variable := range d.inorder {
-		for _, constraint := range variable.Constraints() {
-			if constraint.Anchor() {
-				ids = append(ids, variable.Identifier())
-				break
-			}
-		}
+	ids := make([]deppy.Identifier, len(d.anchors))
+	for i, m := range d.anchors {
+		ids[i] = d.inorder[d.indexOf(m)].Identifier()
 	}
 	return ids
 }
 
+// AnchorLits returns the literals of every Variable with at least one
+// "Anchor" constraint, in the Order they appear in the input.
+func (d *litMapping) AnchorLits() []z.Lit {
+	return d.anchors
+}
+
+// Candidates returns the preference lists of the Variable corresponding
+// to the provided literal, one per non-empty choice of its Constraints
+// (see deppy.ChoicesOf). The returned slices must not be modified.
+// Identifiers in the lists that don't identify any Variable are
+// reported as errors, and appear as z.LitNull in the lists.
+func (d *litMapping) Candidates(m z.Lit) [][]z.Lit {
+	if i := d.indexOf(m); i >= 0 {
+		for _, id := range d.missing[i] {
+			d.errs = append(d.errs, fmt.Errorf("variable %q referenced but not provided", id))
+		}
+		delete(d.missing, i)
+		return d.candidates[i]
+	}
+	d.errs = append(d.errs, fmt.Errorf("no variable corresponding to %s", m))
+	return nil
+}
+
 func (d *litMapping) Variables(g inter.S) []deppy.Variable {
 	var result []deppy.Variable
-	for _, i := range d.inorder {
-		if g.Value(d.LitOf(i.Identifier())) {
-			result = append(result, i)
+	for i, variable := range d.inorder {
+		if g.Value(d.litAt(i)) {
+			result = append(result, variable)
 		}
 	}
 	return result
@@ -196,9 +356,8 @@ func (d *litMapping) Lits(dst []z.Lit) []z.Lit {
 		dst = make([]z.Lit, 0, len(d.inorder))
 	}
 	dst = dst[:0]
-	for _, i := range d.inorder {
-		m := d.LitOf(i.Identifier())
-		dst = append(dst, m)
+	for i := range d.inorder {
+		dst = append(dst, d.litAt(i))
 	}
 	return dst
 }
@@ -207,7 +366,7 @@ func (d *litMapping) Conflicts(g inter.Assumable) []deppy.AppliedConstraint {
 	whys := g.Why(nil)
 	as := make([]deppy.AppliedConstraint, 0, len(whys))
 	for _, why := range whys {
-		if a, ok := d.constraints[why]; ok {
+		if a, ok := d.appliedOf(why); ok {
 			as = append(as, a)
 		}
 	}
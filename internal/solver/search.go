@@ -25,9 +25,9 @@ type guess struct {
 type search struct {
 	s                      inter.S
 	lits                   *litMapping
-	assumptions            map[z.Lit]struct{} // set of assumed lits - duplicates guess stack - for fast lookup
-	guesses                []guess            // stack of assumed guesses
-	headChoice, tailChoice *choice            // deque of unmade choices
+	assumed                []bool  // assumed Variables by input position - duplicates guess stack - for fast lookup
+	guesses                []guess // stack of assumed guesses
+	headChoice, tailChoice *choice // deque of unmade choices
 	tracer                 deppy.Tracer
 	result                 int
 	buffer                 []z.Lit
@@ -47,7 +47,7 @@ func (h *search) PushGuess() {
 	// Check whether or not this choice can be satisfied by an
 	// existing assumption.
 	for _, m := range g.candidates {
-		if _, ok := h.assumptions[m]; ok {
+		if h.isAssumed(m) {
 			g.m = z.LitNull
 			break
 		}
@@ -58,22 +58,12 @@ func (h *search) PushGuess() {
 		return
 	}
 
-	variable := h.lits.VariableOf(g.m)
-	for _, constraint := range variable.Constraints() {
-		var ms []z.Lit
-		for _, dependency := range constraint.Order() {
-			ms = append(ms, h.lits.LitOf(dependency))
-		}
-		if len(ms) > 0 {
-			h.guesses[len(h.guesses)-1].children++
-			h.PushChoiceBack(choice{candidates: ms})
-		}
+	for _, ms := range h.lits.Candidates(g.m) {
+		h.guesses[len(h.guesses)-1].children++
+		h.PushChoiceBack(choice{candidates: ms})
 	}
 
-	if h.assumptions == nil {
-		h.assumptions = make(map[z.Lit]struct{})
-	}
-	h.assumptions[g.m] = struct{}{}
+	h.setAssumed(g.m, true)
 	h.s.Assume(g.m)
 	h.result, h.buffer = h.s.Test(h.buffer)
 }
@@ -82,7 +72,7 @@ func (h *search) PopGuess() {
 	g := h.guesses[len(h.guesses)-1]
 	h.guesses = h.guesses[:len(h.guesses)-1]
 	if g.m != z.LitNull {
-		delete(h.assumptions, g.m)
+		h.setAssumed(g.m, false)
 		h.result = h.s.Untest()
 	}
 	for g.children > 0 {
@@ -99,6 +89,22 @@ func (h *search) PopGuess() {
 	h.PushChoiceFront(c)
 }
 
+func (h *search) isAssumed(m z.Lit) bool {
+	i := h.lits.indexOf(m)
+	return i >= 0 && i < len(h.assumed) && h.assumed[i]
+}
+
+func (h *search) setAssumed(m z.Lit, assumed bool) {
+	i := h.lits.indexOf(m)
+	if i < 0 {
+		return
+	}
+	if h.assumed == nil {
+		h.assumed = make([]bool, len(h.lits.inorder))
+	}
+	h.assumed[i] = assumed
+}
+
 func (h *search) PushChoiceFront(c choice) {
 	if h.headChoice == nil {
 		h.headChoice = &c
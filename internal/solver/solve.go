@@ -49,11 +49,7 @@ func (s *solver) Solve(ctx context.Context) (result []deppy.Variable, err error)
 	s.litMap.AddConstraints(s.g)
 
 	// collect literals of all mandatory variables to assume as a baseline
-	anchors := s.litMap.AnchorIdentifiers()
-	assumptions := make([]z.Lit, len(anchors))
-	for i := range s.litMap.AnchorIdentifiers() {
-		assumptions[i] = s.litMap.LitOf(anchors[i])
-	}
+	assumptions := s.litMap.AnchorLits()
 
 	// assume that all constraints hold
 	s.litMap.AssumeConstraints(s.g)
@@ -71,6 +67,29 @@ func (s *solver) Solve(ctx context.Context) (result []deppy.Variable, err error)
 	case satisfiable:
 		s.buffer = s.litMap.Lits(s.buffer)
 		var extras, excluded []z.Lit
+		for _, m := range s.buffer {
+			if _, ok := aset[m]; !ok {
+				excluded = append(excluded, m.Not())
+			}
+		}
+
+		// in the common case, nothing beyond the assumptions
+		// made during search needs to be selected
+		s.g.Assume(assumptions...)
+		s.g.Assume(excluded...)
+		if s.g.Solve() == satisfiable {
+			return s.litMap.Variables(s.g), nil
+		}
+
+		// otherwise, the constraints require additional
+		// variables (e.g. a cardinality lower bound), so find a
+		// complete model consistent with the search's guesses
+		// and minimize the extras it selects
+		s.g.Assume(assumptions...)
+		if s.g.Solve() != satisfiable {
+			return nil, fmt.Errorf("unexpected internal error")
+		}
+		excluded = excluded[:0]
 		for _, m := range s.buffer {
 			if _, ok := aset[m]; ok {
 				continue
@@ -104,7 +123,7 @@ func (s *solver) Solve(ctx context.Context) (result []deppy.Variable, err error)
 }
 
 func NewSolver(options ...Option) (Solver, error) {
-	s := solver{g: gini.New()}
+	var s solver
 	for _, option := range append(options, defaults...) {
 		if err := option(&s); err != nil {
 			return nil, err
@@ -139,6 +158,15 @@ var defaults = []Option{
 		}
 		return nil
 	},
+	func(s *solver) error {
+		if s.g == nil {
+			// size the solver up front to avoid repeatedly
+			// growing its variable tables while the circuit is
+			// translated to CNF
+			s.g = gini.NewVc(s.litMap.c.Len(), s.litMap.c.Len())
+		}
+		return nil
+	},
 	func(s *solver) error {
 		if s.tracer == nil {
 			s.tracer = DefaultTracer{}
@@ -9,6 +9,7 @@ import (
 	"sort"
 	"testing"
 
+	"github.com/go-air/gini/z"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/operator-framework/deppy/pkg/deppy/constraint"
@@ -241,6 +242,270 @@ func TestSolve(t *testing.T) {
 			},
 			Installed: []deppy.Identifier{"a", "b", "y"},
 		},
+		{
+			Name: "at least constraint selects additional variables",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.AtLeast(2, "x", "y", "z")),
+				variable("x"),
+				variable("y"),
+				variable("z", constraint.Prohibited()),
+			},
+			Installed: []deppy.Identifier{"a", "x", "y"},
+		},
+		{
+			Name: "at least constraint prevents resolution",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.AtLeast(2, "x", "y")),
+				variable("x", constraint.Prohibited()),
+				variable("y"),
+			},
+			Error: deppy.NotSatisfiable{
+				{
+					Variable:   variable("a", constraint.Mandatory(), constraint.AtLeast(2, "x", "y")),
+					Constraint: constraint.AtLeast(2, "x", "y"),
+				},
+				{
+					Variable:   variable("x", constraint.Prohibited()),
+					Constraint: constraint.Prohibited(),
+				},
+			},
+		},
+		{
+			Name: "exactly constraint selects the required number of variables",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Exactly(2, "x", "y", "z")),
+				variable("x"),
+				variable("y", constraint.Prohibited()),
+				variable("z"),
+			},
+			Installed: []deppy.Identifier{"a", "x", "z"},
+		},
+		{
+			Name: "exactly one constraint prevents resolution",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.ExactlyOne("x", "y")),
+				variable("x", constraint.Mandatory()),
+				variable("y", constraint.Mandatory()),
+			},
+			Error: deppy.NotSatisfiable{
+				{
+					Variable:   variable("a", constraint.Mandatory(), constraint.ExactlyOne("x", "y")),
+					Constraint: constraint.ExactlyOne("x", "y"),
+				},
+				{
+					Variable:   variable("x", constraint.Mandatory()),
+					Constraint: constraint.Mandatory(),
+				},
+				{
+					Variable:   variable("y", constraint.Mandatory()),
+					Constraint: constraint.Mandatory(),
+				},
+			},
+		},
+		{
+			Name: "implication applies consequent when antecedent holds",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Implies(constraint.Mandatory(), constraint.Any(
+					constraint.Dependency("b"),
+					constraint.All(constraint.Dependency("c"), constraint.Negate(constraint.Dependency("d"))),
+				))),
+				variable("b", constraint.Prohibited()),
+				variable("c"),
+				variable("d"),
+			},
+			Installed: []deppy.Identifier{"a", "c"},
+		},
+		{
+			Name: "implication doesn't select the candidates of its consequent when its antecedent is false",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Implies(constraint.Selected("x"), constraint.Dependency("b"))),
+				variable("b"),
+				variable("x"),
+			},
+			Installed: []deppy.Identifier{"a"},
+		},
+		{
+			Name: "equivalence doesn't select the candidates of a side that need not hold",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Equiv(constraint.Selected("x"), constraint.Dependency("b"))),
+				variable("b"),
+				variable("x"),
+			},
+			Installed: []deppy.Identifier{"a"},
+		},
+		{
+			Name: "implication prefers the candidates of its consequent when its subject is selected",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Implies(constraint.Mandatory(), constraint.Dependency("b1", "b2"))),
+				variable("b1", constraint.Dependency("c")),
+				variable("b2"),
+				variable("c"),
+			},
+			Installed: []deppy.Identifier{"a", "b1", "c"},
+		},
+		{
+			Name: "preferences of combined constraints are followed separately",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.All(constraint.Dependency("b1", "b2"), constraint.Dependency("c1", "c2"))),
+				variable("b1"),
+				variable("b2"),
+				variable("c1", constraint.Dependency("d")),
+				variable("c2"),
+				variable("d"),
+			},
+			Installed: []deppy.Identifier{"a", "b1", "c1", "d"},
+		},
+		{
+			Name: "composed constraints prevent resolution",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.All(constraint.Dependency("b"), constraint.Conflict("c"))),
+				variable("b", constraint.Equiv(constraint.Mandatory(), constraint.Dependency("c"))),
+				variable("c"),
+			},
+			Error: deppy.NotSatisfiable{
+				{
+					Variable:   variable("a", constraint.Mandatory(), constraint.All(constraint.Dependency("b"), constraint.Conflict("c"))),
+					Constraint: constraint.Mandatory(),
+				},
+				{
+					Variable:   variable("a", constraint.Mandatory(), constraint.All(constraint.Dependency("b"), constraint.Conflict("c"))),
+					Constraint: constraint.All(constraint.Dependency("b"), constraint.Conflict("c")),
+				},
+				{
+					Variable:   variable("b", constraint.Equiv(constraint.Mandatory(), constraint.Dependency("c"))),
+					Constraint: constraint.Equiv(constraint.Mandatory(), constraint.Dependency("c")),
+				},
+			},
+		},
+		{
+			Name: "selected constraint is independent of its subject",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory()),
+				variable("b", constraint.Any(constraint.Selected("x"), constraint.Selected("y"))),
+				variable("x", constraint.Prohibited()),
+				variable("y"),
+			},
+			Installed: []deppy.Identifier{"a", "y"},
+		},
+		{
+			Name: "weighted sum constraint forces cheaper alternative",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Dependency("x", "y")),
+				variable("b", constraint.Mandatory(), constraint.Dependency("z")),
+				variable("budget", constraint.WeightedAtMost(5, map[deppy.Identifier]int{"x": 4, "y": 2, "z": 3})),
+				variable("x"),
+				variable("y"),
+				variable("z"),
+			},
+			Installed: []deppy.Identifier{"a", "b", "y", "z"},
+		},
+		{
+			Name: "weighted sum constraint prevents resolution",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.WeightedAtLeast(6, map[deppy.Identifier]int{"x": 4, "y": 3, "z": -1})),
+				variable("x"),
+				variable("y", constraint.Prohibited()),
+				variable("z"),
+			},
+			Error: deppy.NotSatisfiable{
+				{
+					Variable:   variable("a", constraint.Mandatory(), constraint.WeightedAtLeast(6, map[deppy.Identifier]int{"x": 4, "y": 3, "z": -1})),
+					Constraint: constraint.WeightedAtLeast(6, map[deppy.Identifier]int{"x": 4, "y": 3, "z": -1}),
+				},
+				{
+					Variable:   variable("y", constraint.Prohibited()),
+					Constraint: constraint.Prohibited(),
+				},
+			},
+		},
+		{
+			Name: "weighted exactly constraint selects matching variables",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.WeightedExactly(7, map[deppy.Identifier]int{"x": 4, "y": 2, "z": 3, "w": 5})),
+				variable("x"),
+				variable("y"),
+				variable("z"),
+				variable("w", constraint.Prohibited()),
+			},
+			Installed: []deppy.Identifier{"a", "x", "z"},
+		},
+		{
+			Name: "annotated constraints behave like the constraints they wrap",
+			Variables: []deppy.Variable{
+				variable("a", constraint.WithSource(constraint.Mandatory(), "user")),
+				variable("b", constraint.Prohibited(), constraint.WithReason(constraint.Dependency("a"), "b needs a")),
+			},
+			Installed: []deppy.Identifier{"a"},
+		},
+		{
+			Name: "constraint metadata is reported with conflicts",
+			Variables: []deppy.Variable{
+				variable("a", constraint.WithSource(constraint.Mandatory(), "user")),
+				variable("b", constraint.Mandatory(), constraint.WithSource(constraint.Conflict("a"), "catalog")),
+			},
+			Error: deppy.NotSatisfiable{
+				{
+					Variable:   variable("a", constraint.WithSource(constraint.Mandatory(), "user")),
+					Constraint: constraint.WithSource(constraint.Mandatory(), "user"),
+					Metadata:   deppy.Metadata{Source: "user"},
+				},
+				{
+					Variable:   variable("b", constraint.Mandatory(), constraint.WithSource(constraint.Conflict("a"), "catalog")),
+					Constraint: constraint.Mandatory(),
+				},
+				{
+					Variable:   variable("b", constraint.Mandatory(), constraint.WithSource(constraint.Conflict("a"), "catalog")),
+					Constraint: constraint.WithSource(constraint.Conflict("a"), "catalog"),
+					Metadata:   deppy.Metadata{Source: "catalog"},
+				},
+			},
+		},
+		{
+			Name: "equivalent variables are selected together",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory()),
+				variable("x", constraint.Iff("a")),
+				variable("y", constraint.Iff("z")),
+				variable("z"),
+			},
+			Installed: []deppy.Identifier{"a", "x"},
+		},
+		{
+			Name: "xor selects one of two variables",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Dependency("x")),
+				variable("x", constraint.Xor("y")),
+				variable("y"),
+				variable("z", constraint.Xor("a")),
+			},
+			Installed: []deppy.Identifier{"a", "x"},
+		},
+		{
+			Name: "equivalence and xor prevent resolution",
+			Variables: []deppy.Variable{
+				variable("a", constraint.Mandatory(), constraint.Iff("b")),
+				variable("b", constraint.Xor("c")),
+				variable("c", constraint.Mandatory()),
+			},
+			Error: deppy.NotSatisfiable{
+				{
+					Variable:   variable("a", constraint.Mandatory(), constraint.Iff("b")),
+					Constraint: constraint.Mandatory(),
+				},
+				{
+					Variable:   variable("a", constraint.Mandatory(), constraint.Iff("b")),
+					Constraint: constraint.Iff("b"),
+				},
+				{
+					Variable:   variable("b", constraint.Xor("c")),
+					Constraint: constraint.Xor("c"),
+				},
+				{
+					Variable:   variable("c", constraint.Mandatory()),
+					Constraint: constraint.Mandatory(),
+				},
+			},
+		},
 		{
 			Name: "foo two dependencies satisfied by one variable",
 			Variables: []deppy.Variable{
@@ -357,3 +622,220 @@ func TestDuplicateIdentifier(t *testing.T) {
 	}))
 	assert.Equal(t, DuplicateIdentifier("a"), err)
 }
+
+// preferring is a Constraint that only lists ids as candidates,
+// without constraining them.
+type preferring []deppy.Identifier
+
+func (c preferring) String(subject deppy.Identifier) string {
+	return fmt.Sprintf("%s prefers %v", subject, []deppy.Identifier(c))
+}
+
+func (c preferring) Apply(deppy.LitMapping, deppy.Identifier) z.Lit {
+	return z.LitNull
+}
+
+func (c preferring) Order() []deppy.Identifier {
+	return c
+}
+
+func (c preferring) Anchor() bool {
+	return false
+}
+
+func TestUnknownCandidate(t *testing.T) {
+	t.Run("is ignored if its variable is not guessed", func(t *testing.T) {
+		s, err := NewSolver(WithInput([]deppy.Variable{
+			variable("a", constraint.Mandatory(), constraint.Dependency("b", "c")),
+			variable("b"),
+			variable("c", preferring{"missing"}),
+		}))
+		assert.NoError(t, err)
+		installed, err := s.Solve(context.TODO())
+		assert.NoError(t, err)
+		ids := make([]deppy.Identifier, len(installed))
+		for i, v := range installed {
+			ids[i] = v.Identifier()
+		}
+		assert.Equal(t, []deppy.Identifier{"a", "b"}, ids)
+	})
+
+	t.Run("is reported once its variable is guessed", func(t *testing.T) {
+		s, err := NewSolver(WithInput([]deppy.Variable{
+			variable("a", constraint.Mandatory(), constraint.Dependency("b", "c"), preferring{"missing"}),
+			variable("b"),
+			variable("c"),
+		}))
+		assert.NoError(t, err)
+		_, err = s.Solve(context.TODO())
+		assert.EqualError(t, err, `1 errors encountered: variable "missing" referenced but not provided`)
+	})
+}
+
+// requiresAll is a ConstraintV2 that requires every Variable in ids,
+// sharing the conjunction of their literals between constraints.
+type requiresAll struct {
+	ids    []deppy.Identifier
+	builds *int
+}
+
+func (c requiresAll) String(subject deppy.Identifier) string {
+	return fmt.Sprintf("%s requires all of %v", subject, c.ids)
+}
+
+func (c requiresAll) Apply(b deppy.Builder, subject deppy.Identifier) (z.Lit, error) {
+	all, err := b.Named(fmt.Sprintf("all%v", c.ids), func() (z.Lit, error) {
+		*c.builds++
+		ms := make([]z.Lit, len(c.ids))
+		for i, id := range c.ids {
+			m, err := b.Lookup(id)
+			if err != nil {
+				return z.LitNull, err
+			}
+			ms[i] = m
+		}
+		// route the conjunction through an auxiliary literal
+		aux := b.NewLit()
+		return b.LogicCircuit().And(aux, b.LogicCircuit().Ands(ms...)), nil
+	})
+	if err != nil {
+		return z.LitNull, err
+	}
+	return b.LogicCircuit().Implies(b.LitOf(subject), all), nil
+}
+
+func (c requiresAll) Order() []deppy.Identifier {
+	return c.ids
+}
+
+func (c requiresAll) Anchor() bool {
+	return false
+}
+
+// annotatedRequiresAll is a requiresAll implementing the optional
+// interfaces of Constraints.
+type annotatedRequiresAll struct {
+	requiresAll
+}
+
+func (c annotatedRequiresAll) Metadata() deppy.Metadata {
+	return deppy.Metadata{Reason: "needs b and c"}
+}
+
+func (c annotatedRequiresAll) References() []deppy.Identifier {
+	return append(append([]deppy.Identifier{}, c.ids...), "d")
+}
+
+func (c annotatedRequiresAll) Choices() [][]deppy.Identifier {
+	choices := make([][]deppy.Identifier, len(c.ids))
+	for i, id := range c.ids {
+		choices[i] = []deppy.Identifier{id}
+	}
+	return choices
+}
+
+func TestConstraintV2(t *testing.T) {
+	t.Run("shares named sub-circuits", func(t *testing.T) {
+		var builds int
+		s, err := NewSolver(WithInput([]deppy.Variable{
+			variable("a", constraint.Mandatory(), deppy.WrapV2(requiresAll{ids: []deppy.Identifier{"b", "c"}, builds: &builds})),
+			variable("x", deppy.WrapV2(requiresAll{ids: []deppy.Identifier{"b", "c"}, builds: &builds})),
+			variable("b"),
+			variable("c"),
+		}))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, builds)
+
+		installed, err := s.Solve(context.TODO())
+		assert.NoError(t, err)
+		ids := make([]deppy.Identifier, len(installed))
+		for i, v := range installed {
+			ids[i] = v.Identifier()
+		}
+		assert.ElementsMatch(t, []deppy.Identifier{"a", "b", "c"}, ids)
+	})
+
+	t.Run("returns errors from apply", func(t *testing.T) {
+		var builds int
+		_, err := NewSolver(WithInput([]deppy.Variable{
+			variable("a", deppy.WrapV2(requiresAll{ids: []deppy.Identifier{"missing"}, builds: &builds})),
+		}))
+		assert.EqualError(t, err, `error applying constraint to "a": variable "missing" referenced but not provided`)
+	})
+
+	t.Run("returns errors from nested constraints", func(t *testing.T) {
+		missing := func() deppy.Constraint {
+			var builds int
+			return deppy.WrapV2(requiresAll{ids: []deppy.Identifier{"missing"}, builds: &builds})
+		}
+		for _, c := range []deppy.Constraint{
+			constraint.Negate(missing()),
+			constraint.WithReason(missing(), "reason"),
+			constraint.All(constraint.Mandatory(), missing()),
+			constraint.Any(missing()),
+			constraint.Implies(constraint.Mandatory(), constraint.WithSource(missing(), "source")),
+			constraint.Equiv(missing(), constraint.Mandatory()),
+		} {
+			_, err := NewSolver(WithInput([]deppy.Variable{variable("a", c)}))
+			assert.EqualError(t, err, `error applying constraint to "a": variable "missing" referenced but not provided`, c.String("a"))
+		}
+	})
+
+	t.Run("applies nested constraints with the builder", func(t *testing.T) {
+		var builds int
+		s, err := NewSolver(WithInput([]deppy.Variable{
+			variable("a", constraint.Mandatory(), constraint.Implies(constraint.Mandatory(), deppy.WrapV2(requiresAll{ids: []deppy.Identifier{"b", "c"}, builds: &builds}))),
+			variable("x", constraint.WithReason(deppy.WrapV2(requiresAll{ids: []deppy.Identifier{"b", "c"}, builds: &builds}), "reason")),
+			variable("b"),
+			variable("c"),
+		}))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, builds)
+
+		installed, err := s.Solve(context.TODO())
+		assert.NoError(t, err)
+		ids := make([]deppy.Identifier, len(installed))
+		for i, v := range installed {
+			ids[i] = v.Identifier()
+		}
+		assert.ElementsMatch(t, []deppy.Identifier{"a", "b", "c"}, ids)
+	})
+
+	t.Run("forwards optional interfaces", func(t *testing.T) {
+		var builds int
+		wrapped := deppy.WrapV2(requiresAll{ids: []deppy.Identifier{"b"}, builds: &builds})
+		assert.Equal(t, deppy.Metadata{}, deppy.MetadataOf(wrapped))
+		assert.Equal(t, []deppy.Identifier{"b"}, deppy.ReferencesOf(wrapped))
+		assert.Equal(t, [][]deppy.Identifier{{"b"}}, deppy.ChoicesOf(wrapped))
+
+		wrapped = deppy.WrapV2(annotatedRequiresAll{requiresAll: requiresAll{ids: []deppy.Identifier{"b", "c"}, builds: &builds}})
+		assert.Equal(t, deppy.Metadata{Reason: "needs b and c"}, deppy.MetadataOf(wrapped))
+		assert.Equal(t, []deppy.Identifier{"b", "c", "d"}, deppy.ReferencesOf(wrapped))
+		assert.Equal(t, [][]deppy.Identifier{{"b"}, {"c"}}, deppy.ChoicesOf(wrapped))
+
+		s, err := NewSolver(WithInput([]deppy.Variable{
+			variable("a", constraint.Mandatory(), wrapped),
+			variable("b", constraint.Prohibited()),
+			variable("c"),
+		}))
+		assert.NoError(t, err)
+		_, err = s.Solve(context.TODO())
+		var notSatisfiable deppy.NotSatisfiable
+		assert.ErrorAs(t, err, &notSatisfiable)
+		assert.Contains(t, notSatisfiable, deppy.AppliedConstraint{
+			Variable:   variable("a", constraint.Mandatory(), wrapped),
+			Constraint: wrapped,
+			Metadata:   deppy.Metadata{Reason: "needs b and c"},
+		})
+	})
+
+	t.Run("adapts constraints", func(t *testing.T) {
+		adapted := deppy.AsV2(constraint.Mandatory())
+		assert.Equal(t, deppy.AdaptedV1{Constraint: constraint.Mandatory()}, adapted)
+		assert.True(t, adapted.Anchor())
+		assert.Equal(t, "a is mandatory", adapted.String("a"))
+
+		v2 := requiresAll{ids: []deppy.Identifier{"b"}}
+		assert.Equal(t, v2, deppy.AsV2(deppy.WrapV2(v2)))
+	})
+}
@@ -67,15 +67,109 @@ type Constraint interface {
 	Anchor() bool
 }
 
+// Metadata describes where a Constraint came from and why it exists.
+type Metadata struct {
+	// Source names the VariableSource, request or other producer
+	// of the Constraint.
+	Source string
+	// Reason is a human-readable explanation of the Constraint.
+	Reason string
+	// Labels are arbitrary key-value pairs for use by callers.
+	Labels map[string]string
+}
+
+// IsZero returns true if the receiver carries no information.
+func (m Metadata) IsZero() bool {
+	return m.Source == "" && m.Reason == "" && len(m.Labels) == 0
+}
+
+// String implements fmt.Stringer. Labels are not included.
+func (m Metadata) String() string {
+	var s []string
+	if m.Reason != "" {
+		s = append(s, m.Reason)
+	}
+	if m.Source != "" {
+		s = append(s, fmt.Sprintf("from %s", m.Source))
+	}
+	return strings.Join(s, ", ")
+}
+
+// Annotated is implemented by Constraints that carry
+// Metadata.
+type Annotated interface {
+	Constraint
+	Metadata() Metadata
+}
+
+// MetadataOf returns the Metadata carried by constraint, or the zero
+// Metadata if it carries none.
+func MetadataOf(constraint Constraint) Metadata {
+	if annotated, ok := constraint.(Annotated); ok {
+		return annotated.Metadata()
+	}
+	return Metadata{}
+}
+
+// Referrer is implemented by Constraints that can report every
+// Variable they mention. It allows tooling to build the graph of
+// Variables and Constraints without applying them.
+type Referrer interface {
+	// References returns the Identifiers of the Variables named by
+	// the Constraint. The Variable the Constraint is applied to is
+	// implicit and is not included unless it is named explicitly.
+	References() []Identifier
+}
+
+// ReferencesOf returns the Identifiers of the Variables mentioned by
+// constraint. Constraints that don't implement Referrer are assumed to
+// mention only the Identifiers returned by their Order method.
+func ReferencesOf(constraint Constraint) []Identifier {
+	if referrer, ok := constraint.(Referrer); ok {
+		return referrer.References()
+	}
+	return constraint.Order()
+}
+
+// Chooser is implemented by Constraints whose preferences consist of
+// several independent choices, such as a combination of Constraints
+// that each have their own Order. The solver makes every choice
+// separately, preferring candidates in the order they are listed.
+type Chooser interface {
+	// Choices returns the preference lists of the Constraint, one
+	// per choice.
+	Choices() [][]Identifier
+}
+
+// ChoicesOf returns the preference lists of constraint: those returned
+// by Choices if it implements Chooser, and otherwise its Order, if not
+// empty, as a single choice.
+func ChoicesOf(constraint Constraint) [][]Identifier {
+	if chooser, ok := constraint.(Chooser); ok {
+		return chooser.Choices()
+	}
+	if order := constraint.Order(); len(order) > 0 {
+		return [][]Identifier{order}
+	}
+	return nil
+}
+
 // AppliedConstraint values compose a single Constraint with the
 // Variable it applies to.
 type AppliedConstraint struct {
 	Variable   Variable
 	Constraint Constraint
+	// Metadata is the Metadata carried by Constraint, if any.
+	Metadata Metadata
 }
 
 // String implements fmt.Stringer and returns a human-readable message
-// representing the receiver.
+// representing the receiver. The message includes the reason and
+// source of the constraint when they are known.
 func (a AppliedConstraint) String() string {
-	return a.Constraint.String(a.Variable.Identifier())
+	msg := a.Constraint.String(a.Variable.Identifier())
+	if md := a.Metadata.String(); md != "" {
+		return fmt.Sprintf("%s (%s)", msg, md)
+	}
+	return msg
 }
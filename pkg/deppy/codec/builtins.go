@@ -0,0 +1,420 @@
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint"
+)
+
+type identifiersValue struct {
+	IDs []deppy.Identifier `json:"ids,omitempty"`
+}
+
+type identifierValue struct {
+	ID deppy.Identifier `json:"id"`
+}
+
+type cardinalityValue struct {
+	N   int                `json:"n"`
+	IDs []deppy.Identifier `json:"ids,omitempty"`
+}
+
+type orValue struct {
+	Operand          deppy.Identifier `json:"operand"`
+	IsSubjectNegated bool             `json:"subjectNegated,omitempty"`
+	IsOperandNegated bool             `json:"operandNegated,omitempty"`
+}
+
+type userFriendlyValue struct {
+	Constraint Constraint `json:"constraint"`
+	// Message is the message rendered for the subject at encoding
+	// time, since the formatter itself cannot be serialized. It is
+	// not rendered again when decoding.
+	Message string `json:"message"`
+}
+
+type metadataValue struct {
+	Source string            `json:"source,omitempty"`
+	Reason string            `json:"reason,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type annotatedValue struct {
+	Metadata   metadataValue `json:"metadata"`
+	Constraint Constraint    `json:"constraint"`
+}
+
+type clauseLiteralValue struct {
+	ID      deppy.Identifier `json:"id"`
+	Negated bool             `json:"negated,omitempty"`
+}
+
+type clauseValue struct {
+	Literals []clauseLiteralValue `json:"literals,omitempty"`
+}
+
+type weightedSumValue struct {
+	Limit    int                      `json:"limit"`
+	Relation string                   `json:"relation"`
+	Weights  map[deppy.Identifier]int `json:"weights"`
+}
+
+type constraintsValue struct {
+	Constraints []Constraint `json:"constraints,omitempty"`
+}
+
+type negateValue struct {
+	Constraint Constraint `json:"constraint"`
+}
+
+type impliesValue struct {
+	Antecedent Constraint `json:"antecedent"`
+	Consequent Constraint `json:"consequent"`
+}
+
+type equivValue struct {
+	Left  Constraint `json:"left"`
+	Right Constraint `json:"right"`
+}
+
+func registerBuiltins(r *Registry) {
+	for _, builtin := range []struct {
+		name      string
+		prototype deppy.Constraint
+		encode    EncodeFunc
+		decode    DecodeFunc
+	}{
+		{"mandatory", &constraint.MandatoryConstraint{}, encodeNothing, decodeNothing(constraint.Mandatory)},
+		{"prohibited", &constraint.ProhibitedConstraint{}, encodeNothing, decodeNothing(constraint.Prohibited)},
+		{"dependency", &constraint.DependencyConstraint{}, encodeDependency, decodeDependency},
+		{"conflict", &constraint.ConflictConstraint{}, encodeConflict, decodeIdentifier(constraint.Conflict)},
+		{"iff", &constraint.IffConstraint{}, encodeIff, decodeIdentifier(constraint.Iff)},
+		{"xor", &constraint.XorConstraint{}, encodeXor, decodeIdentifier(constraint.Xor)},
+		{"atMost", &constraint.AtMostConstraint{}, encodeAtMost, decodeCardinality(constraint.AtMost)},
+		{"atLeast", &constraint.AtLeastConstraint{}, encodeAtLeast, decodeCardinality(constraint.AtLeast)},
+		{"exactly", &constraint.ExactlyConstraint{}, encodeExactly, decodeCardinality(constraint.Exactly)},
+		{"or", &constraint.OrConstraint{}, encodeOr, decodeOr},
+		{"userFriendly", &constraint.UserFriendlyConstraint{}, encodeUserFriendly, decodeUserFriendly},
+		{"annotated", &constraint.AnnotatedConstraint{}, encodeAnnotated, decodeAnnotated},
+		{"selected", &constraint.SelectedConstraint{}, encodeSelected, decodeIdentifier(constraint.Selected)},
+		{"clause", &constraint.ClauseConstraint{}, encodeClause, decodeClause},
+		{"weightedSum", &constraint.WeightedSumConstraint{}, encodeWeightedSum, decodeWeightedSum},
+		{"all", &constraint.AllConstraint{}, encodeChildren, decodeChildren(constraint.All)},
+		{"any", &constraint.AnyConstraint{}, encodeChildren, decodeChildren(constraint.Any)},
+		{"negate", &constraint.NegateConstraint{}, encodeNegate, decodeNegate},
+		{"implies", &constraint.ImpliesConstraint{}, encodeImplies, decodeImplies},
+		{"equiv", &constraint.EquivConstraint{}, encodeEquiv, decodeEquiv},
+	} {
+		if err := r.Register(builtin.name, builtin.prototype, builtin.encode, builtin.decode); err != nil {
+			panic(err)
+		}
+	}
+}
+
+func unmarshalValue(value json.RawMessage, v interface{}) error {
+	if len(value) == 0 {
+		return fmt.Errorf("missing value")
+	}
+	return json.Unmarshal(value, v)
+}
+
+func encodeNothing(*Registry, deppy.Constraint, deppy.Identifier) (interface{}, error) {
+	return nil, nil
+}
+
+func decodeNothing(fn func() deppy.Constraint) DecodeFunc {
+	return func(*Registry, json.RawMessage) (deppy.Constraint, error) {
+		return fn(), nil
+	}
+}
+
+func encodeDependency(_ *Registry, c deppy.Constraint, _ deppy.Identifier) (interface{}, error) {
+	return identifiersValue{IDs: c.(*constraint.DependencyConstraint).DependencyIDs()}, nil
+}
+
+func decodeDependency(_ *Registry, value json.RawMessage) (deppy.Constraint, error) {
+	var v identifiersValue
+	if err := unmarshalValue(value, &v); err != nil {
+		return nil, err
+	}
+	return constraint.Dependency(v.IDs...), nil
+}
+
+func encodeConflict(_ *Registry, c deppy.Constraint, _ deppy.Identifier) (interface{}, error) {
+	return identifierValue{ID: c.(*constraint.ConflictConstraint).ConflictingID()}, nil
+}
+
+func encodeIff(_ *Registry, c deppy.Constraint, _ deppy.Identifier) (interface{}, error) {
+	return identifierValue{ID: c.(*constraint.IffConstraint).Identifier()}, nil
+}
+
+func encodeXor(_ *Registry, c deppy.Constraint, _ deppy.Identifier) (interface{}, error) {
+	return identifierValue{ID: c.(*constraint.XorConstraint).Identifier()}, nil
+}
+
+func decodeIdentifier(fn func(deppy.Identifier) deppy.Constraint) DecodeFunc {
+	return func(_ *Registry, value json.RawMessage) (deppy.Constraint, error) {
+		var v identifierValue
+		if err := unmarshalValue(value, &v); err != nil {
+			return nil, err
+		}
+		return fn(v.ID), nil
+	}
+}
+
+func encodeAtMost(_ *Registry, c deppy.Constraint, _ deppy.Identifier) (interface{}, error) {
+	atMost := c.(*constraint.AtMostConstraint)
+	return cardinalityValue{N: atMost.N(), IDs: atMost.Ids()}, nil
+}
+
+func encodeAtLeast(_ *Registry, c deppy.Constraint, _ deppy.Identifier) (interface{}, error) {
+	atLeast := c.(*constraint.AtLeastConstraint)
+	return cardinalityValue{N: atLeast.N(), IDs: atLeast.Ids()}, nil
+}
+
+func encodeExactly(_ *Registry, c deppy.Constraint, _ deppy.Identifier) (interface{}, error) {
+	exactly := c.(*constraint.ExactlyConstraint)
+	return cardinalityValue{N: exactly.N(), IDs: exactly.Ids()}, nil
+}
+
+func decodeCardinality(fn func(int, ...deppy.Identifier) deppy.Constraint) DecodeFunc {
+	return func(_ *Registry, value json.RawMessage) (deppy.Constraint, error) {
+		var v cardinalityValue
+		if err := unmarshalValue(value, &v); err != nil {
+			return nil, err
+		}
+		return fn(v.N, v.IDs...), nil
+	}
+}
+
+func encodeOr(_ *Registry, c deppy.Constraint, _ deppy.Identifier) (interface{}, error) {
+	or := c.(*constraint.OrConstraint)
+	return orValue{
+		Operand:          or.Operand(),
+		IsSubjectNegated: or.IsSubjectNegated(),
+		IsOperandNegated: or.IsOperandNegated(),
+	}, nil
+}
+
+func decodeOr(_ *Registry, value json.RawMessage) (deppy.Constraint, error) {
+	var v orValue
+	if err := unmarshalValue(value, &v); err != nil {
+		return nil, err
+	}
+	return constraint.Or(v.Operand, v.IsSubjectNegated, v.IsOperandNegated), nil
+}
+
+func encodeUserFriendly(r *Registry, c deppy.Constraint, subject deppy.Identifier) (interface{}, error) {
+	userFriendly := c.(*constraint.UserFriendlyConstraint)
+	inner, err := r.EncodeConstraint(userFriendly.Constraint, subject)
+	if err != nil {
+		return nil, err
+	}
+	return userFriendlyValue{Constraint: inner, Message: userFriendly.String(subject)}, nil
+}
+
+func decodeUserFriendly(r *Registry, value json.RawMessage) (deppy.Constraint, error) {
+	var v userFriendlyValue
+	if err := unmarshalValue(value, &v); err != nil {
+		return nil, err
+	}
+	inner, err := r.DecodeConstraint(v.Constraint)
+	if err != nil {
+		return nil, err
+	}
+	return constraint.NewUserFriendlyConstraint(inner, func(deppy.Constraint, deppy.Identifier) string {
+		return v.Message
+	}), nil
+}
+
+func encodeAnnotated(r *Registry, c deppy.Constraint, subject deppy.Identifier) (interface{}, error) {
+	annotated := c.(*constraint.AnnotatedConstraint)
+	inner, err := r.EncodeConstraint(annotated.Constraint, subject)
+	if err != nil {
+		return nil, err
+	}
+	md := annotated.Metadata()
+	return annotatedValue{
+		Metadata:   metadataValue{Source: md.Source, Reason: md.Reason, Labels: md.Labels},
+		Constraint: inner,
+	}, nil
+}
+
+func decodeAnnotated(r *Registry, value json.RawMessage) (deppy.Constraint, error) {
+	var v annotatedValue
+	if err := unmarshalValue(value, &v); err != nil {
+		return nil, err
+	}
+	inner, err := r.DecodeConstraint(v.Constraint)
+	if err != nil {
+		return nil, err
+	}
+	return constraint.WithMetadata(inner, deppy.Metadata{
+		Source: v.Metadata.Source,
+		Reason: v.Metadata.Reason,
+		Labels: v.Metadata.Labels,
+	}), nil
+}
+
+func encodeSelected(_ *Registry, c deppy.Constraint, _ deppy.Identifier) (interface{}, error) {
+	return identifierValue{ID: c.(*constraint.SelectedConstraint).Identifier()}, nil
+}
+
+func encodeClause(_ *Registry, c deppy.Constraint, _ deppy.Identifier) (interface{}, error) {
+	var v clauseValue
+	for _, literal := range c.(*constraint.ClauseConstraint).Literals() {
+		v.Literals = append(v.Literals, clauseLiteralValue{ID: literal.ID, Negated: literal.Negated})
+	}
+	return v, nil
+}
+
+func decodeClause(_ *Registry, value json.RawMessage) (deppy.Constraint, error) {
+	var v clauseValue
+	if err := unmarshalValue(value, &v); err != nil {
+		return nil, err
+	}
+	var literals []constraint.ClauseLiteral
+	for _, literal := range v.Literals {
+		literals = append(literals, constraint.ClauseLiteral{ID: literal.ID, Negated: literal.Negated})
+	}
+	return constraint.Clause(literals...), nil
+}
+
+func encodeWeightedSum(_ *Registry, c deppy.Constraint, _ deppy.Identifier) (interface{}, error) {
+	weighted := c.(*constraint.WeightedSumConstraint)
+	return weightedSumValue{
+		Limit:    weighted.Limit(),
+		Relation: weighted.Relation(),
+		Weights:  weighted.Weights(),
+	}, nil
+}
+
+func decodeWeightedSum(_ *Registry, value json.RawMessage) (deppy.Constraint, error) {
+	var v weightedSumValue
+	if err := unmarshalValue(value, &v); err != nil {
+		return nil, err
+	}
+	switch v.Relation {
+	case "<=":
+		return constraint.WeightedAtMost(v.Limit, v.Weights), nil
+	case ">=":
+		return constraint.WeightedAtLeast(v.Limit, v.Weights), nil
+	case "=":
+		return constraint.WeightedExactly(v.Limit, v.Weights), nil
+	}
+	return nil, fmt.Errorf("unknown relation %q", v.Relation)
+}
+
+// composite is implemented by the constraint combinators.
+type composite interface {
+	Constraints() []deppy.Constraint
+}
+
+func encodeAll(r *Registry, constraints []deppy.Constraint, subject deppy.Identifier) ([]Constraint, error) {
+	result := make([]Constraint, len(constraints))
+	for i, each := range constraints {
+		var err error
+		if result[i], err = r.EncodeConstraint(each, subject); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func decodeAll(r *Registry, constraints ...Constraint) ([]deppy.Constraint, error) {
+	result := make([]deppy.Constraint, len(constraints))
+	for i, each := range constraints {
+		var err error
+		if result[i], err = r.DecodeConstraint(each); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func encodeChildren(r *Registry, c deppy.Constraint, subject deppy.Identifier) (interface{}, error) {
+	children, err := encodeAll(r, c.(composite).Constraints(), subject)
+	if err != nil {
+		return nil, err
+	}
+	return constraintsValue{Constraints: children}, nil
+}
+
+func decodeChildren(fn func(...deppy.Constraint) deppy.Constraint) DecodeFunc {
+	return func(r *Registry, value json.RawMessage) (deppy.Constraint, error) {
+		var v constraintsValue
+		if err := unmarshalValue(value, &v); err != nil {
+			return nil, err
+		}
+		if len(v.Constraints) == 0 {
+			return fn(), nil
+		}
+		children, err := decodeAll(r, v.Constraints...)
+		if err != nil {
+			return nil, err
+		}
+		return fn(children...), nil
+	}
+}
+
+func encodeNegate(r *Registry, c deppy.Constraint, subject deppy.Identifier) (interface{}, error) {
+	children, err := encodeAll(r, c.(composite).Constraints(), subject)
+	if err != nil {
+		return nil, err
+	}
+	return negateValue{Constraint: children[0]}, nil
+}
+
+func decodeNegate(r *Registry, value json.RawMessage) (deppy.Constraint, error) {
+	var v negateValue
+	if err := unmarshalValue(value, &v); err != nil {
+		return nil, err
+	}
+	children, err := decodeAll(r, v.Constraint)
+	if err != nil {
+		return nil, err
+	}
+	return constraint.Negate(children[0]), nil
+}
+
+func encodeImplies(r *Registry, c deppy.Constraint, subject deppy.Identifier) (interface{}, error) {
+	children, err := encodeAll(r, c.(composite).Constraints(), subject)
+	if err != nil {
+		return nil, err
+	}
+	return impliesValue{Antecedent: children[0], Consequent: children[1]}, nil
+}
+
+func decodeImplies(r *Registry, value json.RawMessage) (deppy.Constraint, error) {
+	var v impliesValue
+	if err := unmarshalValue(value, &v); err != nil {
+		return nil, err
+	}
+	children, err := decodeAll(r, v.Antecedent, v.Consequent)
+	if err != nil {
+		return nil, err
+	}
+	return constraint.Implies(children[0], children[1]), nil
+}
+
+func encodeEquiv(r *Registry, c deppy.Constraint, subject deppy.Identifier) (interface{}, error) {
+	children, err := encodeAll(r, c.(composite).Constraints(), subject)
+	if err != nil {
+		return nil, err
+	}
+	return equivValue{Left: children[0], Right: children[1]}, nil
+}
+
+func decodeEquiv(r *Registry, value json.RawMessage) (deppy.Constraint, error) {
+	var v equivValue
+	if err := unmarshalValue(value, &v); err != nil {
+		return nil, err
+	}
+	children, err := decodeAll(r, v.Left, v.Right)
+	if err != nil {
+		return nil, err
+	}
+	return constraint.Equiv(children[0], children[1]), nil
+}
@@ -0,0 +1,256 @@
+// Package codec marshals and unmarshals deppy problems, i.e. slices of
+// Variables and their Constraints, to and from JSON and YAML.
+//
+// Constraints are serialized as a type name and a type-specific
+// value. Every built-in constraint is registered with the
+// DefaultRegistry; custom constraint types can be made serializable by
+// registering their own encoders and decoders.
+//
+// The messages of user friendly constraints can't be serialized as
+// formatters, so they are recorded as rendered for their subject at
+// encoding time, with the catalog in use then. Decoded, they return
+// that text for every subject, regardless of the catalog in use when
+// decoding; the constraint they wrap is decoded as usual, and can be
+// given a new message with Catalog.Formatter if needed.
+package codec
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+// EncodeFunc converts a Constraint, applied to the given subject, into
+// a value that can be marshaled to JSON. Composite constraints can use
+// the Registry to encode their children.
+type EncodeFunc func(r *Registry, constraint deppy.Constraint, subject deppy.Identifier) (interface{}, error)
+
+// DecodeFunc reconstructs a Constraint from the JSON value produced by
+// the corresponding EncodeFunc.
+type DecodeFunc func(r *Registry, value json.RawMessage) (deppy.Constraint, error)
+
+// UnknownConstraintType is returned when a Constraint's type has no
+// registered encoder.
+type UnknownConstraintType string
+
+func (e UnknownConstraintType) Error() string {
+	return fmt.Sprintf("no encoder registered for constraint type %s", string(e))
+}
+
+// UnknownConstraintName is returned when decoding a constraint whose
+// type name has no registered decoder.
+type UnknownConstraintName string
+
+func (e UnknownConstraintName) Error() string {
+	return fmt.Sprintf("no decoder registered for constraint type %q", string(e))
+}
+
+// Constraint is the serialized form of a Constraint.
+type Constraint struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Variable is the serialized form of a Variable.
+type Variable struct {
+	ID          deppy.Identifier `json:"id"`
+	Constraints []Constraint     `json:"constraints,omitempty"`
+}
+
+type registration struct {
+	name   string
+	encode EncodeFunc
+	decode DecodeFunc
+}
+
+// Registry maps constraint types to the names they are serialized
+// under, and to the functions that encode and decode them. It is safe
+// for concurrent use, including registering while encoding or decoding.
+type Registry struct {
+	mu     sync.RWMutex
+	byType map[reflect.Type]registration
+	byName map[string]registration
+}
+
+// NewRegistry returns a Registry with every built-in constraint
+// registered.
+func NewRegistry() *Registry {
+	r := &Registry{
+		byType: map[reflect.Type]registration{},
+		byName: map[string]registration{},
+	}
+	registerBuiltins(r)
+	return r
+}
+
+// DefaultRegistry is used by the package-level functions.
+var DefaultRegistry = NewRegistry()
+
+// Register makes constraints of the same type as prototype
+// serializable under name. It fails if either the name or the type
+// is already registered.
+func (r *Registry) Register(name string, prototype deppy.Constraint, encode EncodeFunc, decode DecodeFunc) error {
+	t := reflect.TypeOf(prototype)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.byName[name]; ok {
+		return fmt.Errorf("constraint type name %q already registered", name)
+	}
+	if existing, ok := r.byType[t]; ok {
+		return fmt.Errorf("constraint type %s already registered as %q", t, existing.name)
+	}
+	reg := registration{name: name, encode: encode, decode: decode}
+	r.byName[name] = reg
+	r.byType[t] = reg
+	return nil
+}
+
+// EncodeConstraint returns the serialized form of constraint, applied
+// to subject.
+func (r *Registry) EncodeConstraint(constraint deppy.Constraint, subject deppy.Identifier) (Constraint, error) {
+	r.mu.RLock()
+	reg, ok := r.byType[reflect.TypeOf(constraint)]
+	r.mu.RUnlock()
+	if !ok {
+		return Constraint{}, UnknownConstraintType(fmt.Sprintf("%T", constraint))
+	}
+	value, err := reg.encode(r, constraint, subject)
+	if err != nil {
+		return Constraint{}, fmt.Errorf("error encoding %s constraint: %w", reg.name, err)
+	}
+	result := Constraint{Type: reg.name}
+	if value != nil {
+		if result.Value, err = json.Marshal(value); err != nil {
+			return Constraint{}, fmt.Errorf("error encoding %s constraint: %w", reg.name, err)
+		}
+	}
+	return result, nil
+}
+
+// DecodeConstraint reconstructs a Constraint from its serialized form.
+func (r *Registry) DecodeConstraint(constraint Constraint) (deppy.Constraint, error) {
+	r.mu.RLock()
+	reg, ok := r.byName[constraint.Type]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, UnknownConstraintName(constraint.Type)
+	}
+	result, err := reg.decode(r, constraint.Value)
+	if err != nil {
+		return nil, fmt.Errorf("error decoding %s constraint: %w", constraint.Type, err)
+	}
+	return result, nil
+}
+
+// EncodeVariables returns the serialized form of variables.
+func (r *Registry) EncodeVariables(variables []deppy.Variable) ([]Variable, error) {
+	result := make([]Variable, len(variables))
+	for i, variable := range variables {
+		result[i].ID = variable.Identifier()
+		for _, constraint := range variable.Constraints() {
+			c, err := r.EncodeConstraint(constraint, variable.Identifier())
+			if err != nil {
+				return nil, fmt.Errorf("variable %q: %w", variable.Identifier(), err)
+			}
+			result[i].Constraints = append(result[i].Constraints, c)
+		}
+	}
+	return result, nil
+}
+
+// DecodeVariables reconstructs Variables from their serialized form.
+// The Variables returned are SimpleVariables.
+func (r *Registry) DecodeVariables(variables []Variable) ([]deppy.Variable, error) {
+	result := make([]deppy.Variable, len(variables))
+	for i, variable := range variables {
+		v := input.NewSimpleVariable(variable.ID)
+		for _, constraint := range variable.Constraints {
+			c, err := r.DecodeConstraint(constraint)
+			if err != nil {
+				return nil, fmt.Errorf("variable %q: %w", variable.ID, err)
+			}
+			v.AddConstraint(c)
+		}
+		result[i] = v
+	}
+	return result, nil
+}
+
+// Marshal returns the JSON encoding of variables.
+func (r *Registry) Marshal(variables []deppy.Variable) ([]byte, error) {
+	encoded, err := r.EncodeVariables(variables)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(encoded)
+}
+
+// Unmarshal parses JSON-encoded Variables.
+func (r *Registry) Unmarshal(data []byte) ([]deppy.Variable, error) {
+	var encoded []Variable
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, err
+	}
+	return r.DecodeVariables(encoded)
+}
+
+// MarshalYAML returns the YAML encoding of variables. The document has
+// the same structure as the JSON encoding.
+func (r *Registry) MarshalYAML(variables []deppy.Variable) ([]byte, error) {
+	data, err := r.Marshal(variables)
+	if err != nil {
+		return nil, err
+	}
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(document)
+}
+
+// UnmarshalYAML parses YAML-encoded Variables.
+func (r *Registry) UnmarshalYAML(data []byte) ([]deppy.Variable, error) {
+	var document interface{}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+	return r.Unmarshal(data)
+}
+
+// Register registers a constraint type with the DefaultRegistry.
+func Register(name string, prototype deppy.Constraint, encode EncodeFunc, decode DecodeFunc) error {
+	return DefaultRegistry.Register(name, prototype, encode, decode)
+}
+
+// Marshal returns the JSON encoding of variables using the
+// DefaultRegistry.
+func Marshal(variables []deppy.Variable) ([]byte, error) {
+	return DefaultRegistry.Marshal(variables)
+}
+
+// Unmarshal parses JSON-encoded Variables using the DefaultRegistry.
+func Unmarshal(data []byte) ([]deppy.Variable, error) {
+	return DefaultRegistry.Unmarshal(data)
+}
+
+// MarshalYAML returns the YAML encoding of variables using the
+// DefaultRegistry.
+func MarshalYAML(variables []deppy.Variable) ([]byte, error) {
+	return DefaultRegistry.MarshalYAML(variables)
+}
+
+// UnmarshalYAML parses YAML-encoded Variables using the
+// DefaultRegistry.
+func UnmarshalYAML(data []byte) ([]deppy.Variable, error) {
+	return DefaultRegistry.UnmarshalYAML(data)
+}
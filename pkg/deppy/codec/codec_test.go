@@ -0,0 +1,162 @@
+package codec_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/go-air/gini/z"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/codec"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+func TestCodec(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Codec Suite")
+}
+
+type requiresLabel struct {
+	label string
+}
+
+func (c *requiresLabel) String(subject deppy.Identifier) string {
+	return fmt.Sprintf("%s requires label %s", subject, c.label)
+}
+
+func (c *requiresLabel) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	return z.LitNull
+}
+
+func (c *requiresLabel) Order() []deppy.Identifier {
+	return nil
+}
+
+func (c *requiresLabel) Anchor() bool {
+	return false
+}
+
+var _ = Describe("Codec", func() {
+	var variables []deppy.Variable
+
+	BeforeEach(func() {
+		variables = []deppy.Variable{
+			input.NewSimpleVariable("a", constraint.Mandatory(), constraint.Dependency("b", "c"), constraint.Dependency()),
+			input.NewSimpleVariable("b", constraint.Prohibited(), constraint.Conflict("c"), constraint.Or("c", true, false)),
+			input.NewSimpleVariable("c", constraint.AtMost(1, "a", "b"), constraint.AtLeast(1, "a"), constraint.ExactlyOne("a", "b")),
+			input.NewSimpleVariable("d",
+				constraint.Clause(constraint.Positive("a"), constraint.Negative("b")),
+				constraint.WeightedExactly(3, map[deppy.Identifier]int{"a": 1, "b": 2}),
+				constraint.Implies(constraint.Mandatory(), constraint.Any(
+					constraint.Selected("a"),
+					constraint.All(constraint.Dependency("b"), constraint.Negate(constraint.Conflict("c"))),
+				)),
+				constraint.Equiv(constraint.All(), constraint.Any()),
+			),
+			input.NewSimpleVariable("e", constraint.Iff("a"), constraint.Xor("b"),
+				constraint.WithMetadata(constraint.Mandatory(), deppy.Metadata{Source: "user", Reason: "requested", Labels: map[string]string{"team": "x"}})),
+		}
+	})
+
+	It("should round-trip variables through JSON", func() {
+		data, err := codec.Marshal(variables)
+		Expect(err).ToNot(HaveOccurred())
+		decoded, err := codec.Unmarshal(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded).To(Equal(variables))
+	})
+
+	It("should round-trip variables through YAML", func() {
+		data, err := codec.MarshalYAML(variables)
+		Expect(err).ToNot(HaveOccurred())
+		decoded, err := codec.UnmarshalYAML(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded).To(Equal(variables))
+	})
+
+	It("should decode hand-written YAML", func() {
+		decoded, err := codec.UnmarshalYAML([]byte(`
+- id: a
+  constraints:
+  - type: mandatory
+  - type: dependency
+    value:
+      ids: [b]
+- id: b
+`))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded).To(Equal([]deppy.Variable{
+			input.NewSimpleVariable("a", constraint.Mandatory(), constraint.Dependency("b")),
+			input.NewSimpleVariable("b"),
+		}))
+	})
+
+	It("should preserve the messages of user friendly constraints", func() {
+		userFriendly := constraint.NewUserFriendlyConstraint(constraint.Dependency("b"), func(_ deppy.Constraint, subject deppy.Identifier) string {
+			return fmt.Sprintf("%s needs b", subject)
+		})
+		data, err := codec.Marshal([]deppy.Variable{input.NewSimpleVariable("a", userFriendly)})
+		Expect(err).ToNot(HaveOccurred())
+		decoded, err := codec.Unmarshal(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded).To(HaveLen(1))
+		Expect(decoded[0].Constraints()).To(HaveLen(1))
+		c := decoded[0].Constraints()[0].(*constraint.UserFriendlyConstraint)
+		Expect(c.String("a")).To(Equal("a needs b"))
+		Expect(c.String("x")).To(Equal("a needs b"))
+		Expect(c.Constraint).To(Equal(constraint.Dependency("b")))
+	})
+
+	It("should fail on unregistered constraint types", func() {
+		_, err := codec.Marshal([]deppy.Variable{input.NewSimpleVariable("a", &requiresLabel{label: "x"})})
+		Expect(err).To(MatchError(ContainSubstring("no encoder registered for constraint type *codec_test.requiresLabel")))
+		_, err = codec.Unmarshal([]byte(`[{"id":"a","constraints":[{"type":"requiresLabel"}]}]`))
+		Expect(err).To(MatchError(codec.UnknownConstraintName("requiresLabel")))
+	})
+
+	It("should serialize custom constraint types once registered", func() {
+		r := codec.NewRegistry()
+		Expect(r.Register("requiresLabel", &requiresLabel{},
+			func(_ *codec.Registry, c deppy.Constraint, _ deppy.Identifier) (interface{}, error) {
+				return c.(*requiresLabel).label, nil
+			},
+			func(_ *codec.Registry, value json.RawMessage) (deppy.Constraint, error) {
+				c := &requiresLabel{}
+				return c, json.Unmarshal(value, &c.label)
+			},
+		)).To(Succeed())
+		Expect(r.Register("requiresLabel", &requiresLabel{}, nil, nil)).ToNot(Succeed())
+
+		variables := []deppy.Variable{input.NewSimpleVariable("a", constraint.Negate(&requiresLabel{label: "x"}))}
+		data, err := r.Marshal(variables)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(data)).To(Equal(`[{"id":"a","constraints":[{"type":"negate","value":{"constraint":{"type":"requiresLabel","value":"x"}}}]}]`))
+		decoded, err := r.Unmarshal(data)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(decoded).To(Equal(variables))
+	})
+
+	It("should allow registering while encoding and decoding", func() {
+		r := codec.NewRegistry()
+		data, err := r.Marshal(variables)
+		Expect(err).ToNot(HaveOccurred())
+
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			Expect(r.Register("requiresLabel", &requiresLabel{}, nil, nil)).To(Succeed())
+		}()
+		for i := 0; i < 100; i++ {
+			_, err := r.Marshal(variables)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = r.Unmarshal(data)
+			Expect(err).ToNot(HaveOccurred())
+		}
+		<-done
+	})
+})
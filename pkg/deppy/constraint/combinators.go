@@ -0,0 +1,429 @@
+package constraint
+
+import (
+	"github.com/go-air/gini/z"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+// composite is implemented by constraints built out of other
+// constraints. Their messages are set apart when nested.
+type composite interface {
+	Constraints() []deppy.Constraint
+}
+
+type AllConstraint struct {
+	constraints []deppy.Constraint
+}
+
+func (constraint *AllConstraint) String(subject deppy.Identifier) string {
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *AllConstraint) Message(subject deppy.Identifier) Message {
+	return Message{
+		ID:      "all",
+		Subject: subject,
+		Params:  map[string]interface{}{"Constraints": constraint.constraints},
+	}
+}
+
+func (constraint *AllConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	return lm.LogicCircuit().Ands(applyAll(lm, subject, constraint.constraints)...)
+}
+
+func (constraint *AllConstraint) ApplyWith(b deppy.Builder, subject deppy.Identifier) (z.Lit, error) {
+	ms, err := applyAllWith(b, subject, constraint.constraints)
+	if err != nil {
+		return z.LitNull, err
+	}
+	return b.LogicCircuit().Ands(ms...), nil
+}
+
+func (constraint *AllConstraint) Constraints() []deppy.Constraint {
+	return constraint.constraints
+}
+
+func (constraint *AllConstraint) Order() []deppy.Identifier {
+	return mergeOrders(constraint.constraints...)
+}
+
+// Choices returns the choices of every child, since each of them must be
+// satisfied on its own terms.
+func (constraint *AllConstraint) Choices() [][]deppy.Identifier {
+	var choices [][]deppy.Identifier
+	for _, each := range constraint.constraints {
+		choices = append(choices, deppy.ChoicesOf(each)...)
+	}
+	return choices
+}
+
+func (constraint *AllConstraint) References() []deppy.Identifier {
+	return mergeReferences(constraint.constraints...)
+}
+
+func (constraint *AllConstraint) Anchor() bool {
+	for _, each := range constraint.constraints {
+		if each.Anchor() {
+			return true
+		}
+	}
+	return false
+}
+
+// All returns a Constraint that is satisfied only when every one of
+// the given Constraints is satisfied. The solver makes the choices of
+// each of the given Constraints separately, following its preferences,
+// while Order merges their preferences in argument order.
+func All(constraints ...deppy.Constraint) deppy.Constraint {
+	return &AllConstraint{
+		constraints: constraints,
+	}
+}
+
+type AnyConstraint struct {
+	constraints []deppy.Constraint
+}
+
+func (constraint *AnyConstraint) String(subject deppy.Identifier) string {
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *AnyConstraint) Message(subject deppy.Identifier) Message {
+	return Message{
+		ID:      "any",
+		Subject: subject,
+		Params:  map[string]interface{}{"Constraints": constraint.constraints},
+	}
+}
+
+func (constraint *AnyConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	return lm.LogicCircuit().Ors(applyAll(lm, subject, constraint.constraints)...)
+}
+
+func (constraint *AnyConstraint) ApplyWith(b deppy.Builder, subject deppy.Identifier) (z.Lit, error) {
+	ms, err := applyAllWith(b, subject, constraint.constraints)
+	if err != nil {
+		return z.LitNull, err
+	}
+	return b.LogicCircuit().Ors(ms...), nil
+}
+
+func (constraint *AnyConstraint) Constraints() []deppy.Constraint {
+	return constraint.constraints
+}
+
+func (constraint *AnyConstraint) Order() []deppy.Identifier {
+	return mergeOrders(constraint.constraints...)
+}
+
+func (constraint *AnyConstraint) References() []deppy.Identifier {
+	return mergeReferences(constraint.constraints...)
+}
+
+func (constraint *AnyConstraint) Anchor() bool {
+	for _, each := range constraint.constraints {
+		if !each.Anchor() {
+			return false
+		}
+	}
+	return len(constraint.constraints) > 0
+}
+
+// Any returns a Constraint that is satisfied when at least one of the
+// given Constraints is satisfied. The preferences of the given
+// Constraints are merged in argument order.
+func Any(constraints ...deppy.Constraint) deppy.Constraint {
+	return &AnyConstraint{
+		constraints: constraints,
+	}
+}
+
+type NegateConstraint struct {
+	constraint deppy.Constraint
+}
+
+func (constraint *NegateConstraint) String(subject deppy.Identifier) string {
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *NegateConstraint) Message(subject deppy.Identifier) Message {
+	return Message{
+		ID:      "negate",
+		Subject: subject,
+		Params:  map[string]interface{}{"Constraint": constraint.constraint},
+	}
+}
+
+func (constraint *NegateConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	return apply(lm, subject, constraint.constraint).Not()
+}
+
+func (constraint *NegateConstraint) ApplyWith(b deppy.Builder, subject deppy.Identifier) (z.Lit, error) {
+	m, err := applyWith(b, subject, constraint.constraint)
+	if err != nil {
+		return z.LitNull, err
+	}
+	return m.Not(), nil
+}
+
+func (constraint *NegateConstraint) Constraints() []deppy.Constraint {
+	return []deppy.Constraint{constraint.constraint}
+}
+
+func (constraint *NegateConstraint) Order() []deppy.Identifier {
+	// preferences among the candidates of a negated constraint are
+	// meaningless, since none of them are required
+	return nil
+}
+
+func (constraint *NegateConstraint) References() []deppy.Identifier {
+	return deppy.ReferencesOf(constraint.constraint)
+}
+
+func (constraint *NegateConstraint) Anchor() bool {
+	return false
+}
+
+// Negate returns a Constraint that is satisfied only when the given
+// Constraint is not. Negate(Mandatory()) is equivalent to Prohibited().
+func Negate(constraint deppy.Constraint) deppy.Constraint {
+	return &NegateConstraint{
+		constraint: constraint,
+	}
+}
+
+type ImpliesConstraint struct {
+	antecedent deppy.Constraint
+	consequent deppy.Constraint
+}
+
+func (constraint *ImpliesConstraint) String(subject deppy.Identifier) string {
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *ImpliesConstraint) Message(subject deppy.Identifier) Message {
+	return Message{
+		ID:      "implies",
+		Subject: subject,
+		Params:  map[string]interface{}{"Antecedent": constraint.antecedent, "Consequent": constraint.consequent},
+	}
+}
+
+func (constraint *ImpliesConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	return lm.LogicCircuit().Implies(apply(lm, subject, constraint.antecedent), apply(lm, subject, constraint.consequent))
+}
+
+func (constraint *ImpliesConstraint) ApplyWith(b deppy.Builder, subject deppy.Identifier) (z.Lit, error) {
+	ms, err := applyAllWith(b, subject, []deppy.Constraint{constraint.antecedent, constraint.consequent})
+	if err != nil {
+		return z.LitNull, err
+	}
+	return b.LogicCircuit().Implies(ms[0], ms[1]), nil
+}
+
+func (constraint *ImpliesConstraint) Constraints() []deppy.Constraint {
+	return []deppy.Constraint{constraint.antecedent, constraint.consequent}
+}
+
+// Order returns the preferences of the consequent if the antecedent
+// is Mandatory, which holds whenever the solver makes choices for the
+// subject. Otherwise the consequent may not be required, and choosing
+// among its candidates could select them needlessly.
+func (constraint *ImpliesConstraint) Order() []deppy.Identifier {
+	if !isMandatory(constraint.antecedent) {
+		return nil
+	}
+	return constraint.consequent.Order()
+}
+
+func (constraint *ImpliesConstraint) Choices() [][]deppy.Identifier {
+	if !isMandatory(constraint.antecedent) {
+		return nil
+	}
+	return deppy.ChoicesOf(constraint.consequent)
+}
+
+func (constraint *ImpliesConstraint) References() []deppy.Identifier {
+	return mergeReferences(constraint.antecedent, constraint.consequent)
+}
+
+func (constraint *ImpliesConstraint) Anchor() bool {
+	return false
+}
+
+// Implies returns a Constraint that is satisfied when the consequent
+// Constraint is satisfied or the antecedent Constraint is not. For
+// instance, Implies(Mandatory(), Dependency("b")) applied to "a"
+// requires "b" only if "a" is selected.
+func Implies(antecedent deppy.Constraint, consequent deppy.Constraint) deppy.Constraint {
+	return &ImpliesConstraint{
+		antecedent: antecedent,
+		consequent: consequent,
+	}
+}
+
+type EquivConstraint struct {
+	left  deppy.Constraint
+	right deppy.Constraint
+}
+
+func (constraint *EquivConstraint) String(subject deppy.Identifier) string {
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *EquivConstraint) Message(subject deppy.Identifier) Message {
+	return Message{
+		ID:      "equiv",
+		Subject: subject,
+		Params:  map[string]interface{}{"Left": constraint.left, "Right": constraint.right},
+	}
+}
+
+func (constraint *EquivConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	return lm.LogicCircuit().Xor(apply(lm, subject, constraint.left), apply(lm, subject, constraint.right)).Not()
+}
+
+func (constraint *EquivConstraint) ApplyWith(b deppy.Builder, subject deppy.Identifier) (z.Lit, error) {
+	ms, err := applyAllWith(b, subject, []deppy.Constraint{constraint.left, constraint.right})
+	if err != nil {
+		return z.LitNull, err
+	}
+	return b.LogicCircuit().Xor(ms[0], ms[1]).Not(), nil
+}
+
+func (constraint *EquivConstraint) Constraints() []deppy.Constraint {
+	return []deppy.Constraint{constraint.left, constraint.right}
+}
+
+// Order returns the preferences of one side if the other is Mandatory,
+// for the same reason as ImpliesConstraint.Order.
+func (constraint *EquivConstraint) Order() []deppy.Identifier {
+	return mergeOrders(constraint.required()...)
+}
+
+func (constraint *EquivConstraint) Choices() [][]deppy.Identifier {
+	var choices [][]deppy.Identifier
+	for _, c := range constraint.required() {
+		choices = append(choices, deppy.ChoicesOf(c)...)
+	}
+	return choices
+}
+
+// required returns the sides of the receiver that hold whenever its
+// subject is selected, other than Mandatory itself.
+func (constraint *EquivConstraint) required() []deppy.Constraint {
+	switch {
+	case isMandatory(constraint.left):
+		return []deppy.Constraint{constraint.right}
+	case isMandatory(constraint.right):
+		return []deppy.Constraint{constraint.left}
+	}
+	return nil
+}
+
+func (constraint *EquivConstraint) References() []deppy.Identifier {
+	return mergeReferences(constraint.left, constraint.right)
+}
+
+func (constraint *EquivConstraint) Anchor() bool {
+	return false
+}
+
+// Equiv returns a Constraint that is satisfied when either both or
+// neither of the given Constraints are satisfied.
+func Equiv(left deppy.Constraint, right deppy.Constraint) deppy.Constraint {
+	return &EquivConstraint{
+		left:  left,
+		right: right,
+	}
+}
+
+// apply returns the literal of constraint applied to subject. A
+// constraint without a useful representation in the SAT inputs
+// imposes no restriction, so it is treated as true.
+func apply(lm deppy.LitMapping, subject deppy.Identifier, constraint deppy.Constraint) z.Lit {
+	m := constraint.Apply(lm, subject)
+	if m == z.LitNull {
+		return lm.LogicCircuit().T
+	}
+	return m
+}
+
+// applyWith is like apply, except that constraint is applied with
+// deppy.ApplyWith, so that the errors of nested ConstraintV2
+// implementations are returned.
+func applyWith(b deppy.Builder, subject deppy.Identifier, constraint deppy.Constraint) (z.Lit, error) {
+	m, err := deppy.ApplyWith(b, constraint, subject)
+	if err != nil {
+		return z.LitNull, err
+	}
+	if m == z.LitNull {
+		return b.LogicCircuit().T, nil
+	}
+	return m, nil
+}
+
+// isMandatory returns true if constraint is Mandatory, possibly
+// annotated or given a user friendly message.
+func isMandatory(constraint deppy.Constraint) bool {
+	for {
+		switch c := constraint.(type) {
+		case *MandatoryConstraint:
+			return true
+		case *AnnotatedConstraint:
+			constraint = c.Constraint
+		case *UserFriendlyConstraint:
+			constraint = c.Constraint
+		default:
+			return false
+		}
+	}
+}
+
+func applyAll(lm deppy.LitMapping, subject deppy.Identifier, constraints []deppy.Constraint) []z.Lit {
+	ms := make([]z.Lit, len(constraints))
+	for i, each := range constraints {
+		ms[i] = apply(lm, subject, each)
+	}
+	return ms
+}
+
+func applyAllWith(b deppy.Builder, subject deppy.Identifier, constraints []deppy.Constraint) ([]z.Lit, error) {
+	ms := make([]z.Lit, len(constraints))
+	for i, each := range constraints {
+		m, err := applyWith(b, subject, each)
+		if err != nil {
+			return nil, err
+		}
+		ms[i] = m
+	}
+	return ms, nil
+}
+
+// mergeReferences concatenates the references of the given
+// constraints, keeping only the first occurrence of each Identifier.
+func mergeReferences(constraints ...deppy.Constraint) []deppy.Identifier {
+	return mergeIdentifiers(constraints, deppy.ReferencesOf)
+}
+
+// mergeOrders concatenates the preferences of the given constraints,
+// keeping only the first occurrence of each Identifier.
+func mergeOrders(constraints ...deppy.Constraint) []deppy.Identifier {
+	return mergeIdentifiers(constraints, deppy.Constraint.Order)
+}
+
+func mergeIdentifiers(constraints []deppy.Constraint, identifiers func(deppy.Constraint) []deppy.Identifier) []deppy.Identifier {
+	var ids []deppy.Identifier
+	seen := map[deppy.Identifier]struct{}{}
+	for _, each := range constraints {
+		for _, id := range identifiers(each) {
+			if _, ok := seen[id]; ok {
+				continue
+			}
+			seen[id] = struct{}{}
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -2,8 +2,8 @@ package constraint
 
 import (
 	"fmt"
-	"strings"
 
+	"github.com/go-air/gini/logic"
 	"github.com/go-air/gini/z"
 
 	"github.com/operator-framework/deppy/pkg/deppy"
@@ -20,6 +20,18 @@ func (constraint *UserFriendlyConstraint) String(subject deppy.Identifier) strin
 	return constraint.messageFormatter(constraint, subject)
 }
 
+func (constraint *UserFriendlyConstraint) ApplyWith(b deppy.Builder, subject deppy.Identifier) (z.Lit, error) {
+	return deppy.ApplyWith(b, constraint.Constraint, subject)
+}
+
+func (constraint *UserFriendlyConstraint) References() []deppy.Identifier {
+	return deppy.ReferencesOf(constraint.Constraint)
+}
+
+func (constraint *UserFriendlyConstraint) Choices() [][]deppy.Identifier {
+	return deppy.ChoicesOf(constraint.Constraint)
+}
+
 func NewUserFriendlyConstraint(constraint deppy.Constraint, messageFormatter UserFriendlyConstraintMessageFormatter) *UserFriendlyConstraint {
 	return &UserFriendlyConstraint{
 		Constraint:       constraint,
@@ -27,10 +39,84 @@ func NewUserFriendlyConstraint(constraint deppy.Constraint, messageFormatter Use
 	}
 }
 
+type AnnotatedConstraint struct {
+	deppy.Constraint
+	metadata deppy.Metadata
+}
+
+func (constraint *AnnotatedConstraint) Metadata() deppy.Metadata {
+	return constraint.metadata
+}
+
+func (constraint *AnnotatedConstraint) ApplyWith(b deppy.Builder, subject deppy.Identifier) (z.Lit, error) {
+	return deppy.ApplyWith(b, constraint.Constraint, subject)
+}
+
+func (constraint *AnnotatedConstraint) References() []deppy.Identifier {
+	return deppy.ReferencesOf(constraint.Constraint)
+}
+
+func (constraint *AnnotatedConstraint) Choices() [][]deppy.Identifier {
+	return deppy.ChoicesOf(constraint.Constraint)
+}
+
+// WithMetadata returns a Constraint that behaves like the given
+// Constraint and carries the given Metadata. If the given Constraint
+// already carries Metadata, non-empty fields of the new Metadata take
+// precedence and labels are merged.
+func WithMetadata(constraint deppy.Constraint, metadata deppy.Metadata) *AnnotatedConstraint {
+	if annotated, ok := constraint.(*AnnotatedConstraint); ok {
+		constraint, metadata = annotated.Constraint, mergeMetadata(annotated.metadata, metadata)
+	}
+	return &AnnotatedConstraint{
+		Constraint: constraint,
+		metadata:   metadata,
+	}
+}
+
+// WithSource returns a Constraint that behaves like the given
+// Constraint and records source as its origin.
+func WithSource(constraint deppy.Constraint, source string) *AnnotatedConstraint {
+	return WithMetadata(constraint, deppy.Metadata{Source: source})
+}
+
+// WithReason returns a Constraint that behaves like the given
+// Constraint and records reason as its explanation.
+func WithReason(constraint deppy.Constraint, reason string) *AnnotatedConstraint {
+	return WithMetadata(constraint, deppy.Metadata{Reason: reason})
+}
+
+func mergeMetadata(base deppy.Metadata, override deppy.Metadata) deppy.Metadata {
+	if override.Source != "" {
+		base.Source = override.Source
+	}
+	if override.Reason != "" {
+		base.Reason = override.Reason
+	}
+	if len(override.Labels) > 0 {
+		labels := make(map[string]string, len(base.Labels)+len(override.Labels))
+		for k, v := range base.Labels {
+			labels[k] = v
+		}
+		for k, v := range override.Labels {
+			labels[k] = v
+		}
+		base.Labels = labels
+	}
+	return base
+}
+
 type MandatoryConstraint struct{}
 
 func (constraint *MandatoryConstraint) String(subject deppy.Identifier) string {
-	return fmt.Sprintf("%s is mandatory", subject)
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *MandatoryConstraint) Message(subject deppy.Identifier) Message {
+	return Message{
+		ID:      "mandatory",
+		Subject: subject,
+	}
 }
 
 func (constraint *MandatoryConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
@@ -41,6 +127,10 @@ func (constraint *MandatoryConstraint) Order() []deppy.Identifier {
 	return nil
 }
 
+func (constraint *MandatoryConstraint) References() []deppy.Identifier {
+	return nil
+}
+
 func (constraint *MandatoryConstraint) Anchor() bool {
 	return true
 }
@@ -54,7 +144,14 @@ func Mandatory() deppy.Constraint {
 type ProhibitedConstraint struct{}
 
 func (constraint *ProhibitedConstraint) String(subject deppy.Identifier) string {
-	return fmt.Sprintf("%s is ProhibitedConstraint", subject)
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *ProhibitedConstraint) Message(subject deppy.Identifier) Message {
+	return Message{
+		ID:      "prohibited",
+		Subject: subject,
+	}
 }
 
 func (constraint *ProhibitedConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
@@ -65,6 +162,10 @@ func (constraint *ProhibitedConstraint) Order() []deppy.Identifier {
 	return nil
 }
 
+func (constraint *ProhibitedConstraint) References() []deppy.Identifier {
+	return nil
+}
+
 func (constraint *ProhibitedConstraint) Anchor() bool {
 	return false
 }
@@ -86,14 +187,15 @@ type DependencyConstraint struct {
 }
 
 func (constraint *DependencyConstraint) String(subject deppy.Identifier) string {
-	if len(constraint.dependencyIDs) == 0 {
-		return fmt.Sprintf("%s has a DependencyConstraint without any candidates to satisfy it", subject)
-	}
-	s := make([]string, len(constraint.dependencyIDs))
-	for i, each := range constraint.dependencyIDs {
-		s[i] = string(each)
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *DependencyConstraint) Message(subject deppy.Identifier) Message {
+	return Message{
+		ID:      "dependency",
+		Subject: subject,
+		Params:  map[string]interface{}{"IDs": constraint.dependencyIDs},
 	}
-	return fmt.Sprintf("%s requires at least one of %s", subject, strings.Join(s, ", "))
 }
 
 func (constraint *DependencyConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
@@ -112,6 +214,10 @@ func (constraint *DependencyConstraint) Order() []deppy.Identifier {
 	return constraint.dependencyIDs
 }
 
+func (constraint *DependencyConstraint) References() []deppy.Identifier {
+	return constraint.dependencyIDs
+}
+
 func (constraint *DependencyConstraint) Anchor() bool {
 	return false
 }
@@ -132,7 +238,19 @@ type ConflictConstraint struct {
 }
 
 func (constraint *ConflictConstraint) String(subject deppy.Identifier) string {
-	return fmt.Sprintf("%s conflicts with %s", subject, constraint.conflictingID)
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *ConflictConstraint) Message(subject deppy.Identifier) Message {
+	return Message{
+		ID:      "conflict",
+		Subject: subject,
+		Params:  map[string]interface{}{"ID": constraint.conflictingID},
+	}
+}
+
+func (constraint *ConflictConstraint) ConflictingID() deppy.Identifier {
+	return constraint.conflictingID
 }
 
 func (constraint *ConflictConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
@@ -143,6 +261,10 @@ func (constraint *ConflictConstraint) Order() []deppy.Identifier {
 	return nil
 }
 
+func (constraint *ConflictConstraint) References() []deppy.Identifier {
+	return []deppy.Identifier{constraint.conflictingID}
+}
+
 func (constraint *ConflictConstraint) Anchor() bool {
 	return false
 }
@@ -156,18 +278,114 @@ func Conflict(id deppy.Identifier) deppy.Constraint {
 	}
 }
 
+type IffConstraint struct {
+	id deppy.Identifier
+}
+
+func (constraint *IffConstraint) String(subject deppy.Identifier) string {
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *IffConstraint) Message(subject deppy.Identifier) Message {
+	return Message{
+		ID:      "iff",
+		Subject: subject,
+		Params:  map[string]interface{}{"ID": constraint.id},
+	}
+}
+
+func (constraint *IffConstraint) Identifier() deppy.Identifier {
+	return constraint.id
+}
+
+func (constraint *IffConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	return lm.LogicCircuit().Xor(lm.LitOf(subject), lm.LitOf(constraint.id)).Not()
+}
+
+func (constraint *IffConstraint) Order() []deppy.Identifier {
+	return []deppy.Identifier{constraint.id}
+}
+
+func (constraint *IffConstraint) References() []deppy.Identifier {
+	return []deppy.Identifier{constraint.id}
+}
+
+func (constraint *IffConstraint) Anchor() bool {
+	return false
+}
+
+// Iff returns a Constraint that will permit solutions
+// containing both the constrained Variable and the Variable
+// identified by the given Identifier, or neither, but not only one of
+// them.
+func Iff(id deppy.Identifier) deppy.Constraint {
+	return &IffConstraint{
+		id: id,
+	}
+}
+
+type XorConstraint struct {
+	id deppy.Identifier
+}
+
+func (constraint *XorConstraint) String(subject deppy.Identifier) string {
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *XorConstraint) Message(subject deppy.Identifier) Message {
+	return Message{
+		ID:      "xor",
+		Subject: subject,
+		Params:  map[string]interface{}{"ID": constraint.id},
+	}
+}
+
+func (constraint *XorConstraint) Identifier() deppy.Identifier {
+	return constraint.id
+}
+
+func (constraint *XorConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	return lm.LogicCircuit().Xor(lm.LitOf(subject), lm.LitOf(constraint.id))
+}
+
+func (constraint *XorConstraint) Order() []deppy.Identifier {
+	return nil
+}
+
+func (constraint *XorConstraint) References() []deppy.Identifier {
+	return []deppy.Identifier{constraint.id}
+}
+
+func (constraint *XorConstraint) Anchor() bool {
+	return false
+}
+
+// Xor returns a Constraint that will permit solutions containing
+// either the constrained Variable or the Variable identified by the
+// given Identifier, but neither both nor none of them.
+func Xor(id deppy.Identifier) deppy.Constraint {
+	return &XorConstraint{
+		id: id,
+	}
+}
+
 type AtMostConstraint struct {
 	ids []deppy.Identifier
 	n   int
 }
 
 func (constraint *AtMostConstraint) String(subject deppy.Identifier) string {
-	s := make([]string, len(constraint.ids))
-	for i, each := range constraint.ids {
-		s[i] = string(each)
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *AtMostConstraint) Message(subject deppy.Identifier) Message {
+	return Message{
+		ID:      "atMost",
+		Subject: subject,
+		Params:  map[string]interface{}{"N": constraint.n, "IDs": constraint.ids},
 	}
-	return fmt.Sprintf("%s permits at most %d of %s", subject, constraint.n, strings.Join(s, ", "))
 }
+
 func (constraint *AtMostConstraint) N() int {
 	return constraint.n
 }
@@ -177,17 +395,17 @@ func (constraint *AtMostConstraint) Ids() []deppy.Identifier {
 }
 
 func (constraint *AtMostConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
-	ms := make([]z.Lit, len(constraint.ids))
-	for i, each := range constraint.ids {
-		ms[i] = lm.LitOf(each)
-	}
-	return lm.LogicCircuit().CardSort(ms).Leq(constraint.n)
+	return cardSort(lm, constraint.ids).Leq(constraint.n)
 }
 
 func (constraint *AtMostConstraint) Order() []deppy.Identifier {
 	return nil
 }
 
+func (constraint *AtMostConstraint) References() []deppy.Identifier {
+	return constraint.ids
+}
+
 func (constraint *AtMostConstraint) Anchor() bool {
 	return false
 }
@@ -202,6 +420,204 @@ func AtMost(n int, ids ...deppy.Identifier) deppy.Constraint {
 	}
 }
 
+type AtLeastConstraint struct {
+	ids []deppy.Identifier
+	n   int
+}
+
+func (constraint *AtLeastConstraint) String(subject deppy.Identifier) string {
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *AtLeastConstraint) Message(subject deppy.Identifier) Message {
+	return Message{
+		ID:      "atLeast",
+		Subject: subject,
+		Params:  map[string]interface{}{"N": constraint.n, "IDs": constraint.ids},
+	}
+}
+
+func (constraint *AtLeastConstraint) N() int {
+	return constraint.n
+}
+
+func (constraint *AtLeastConstraint) Ids() []deppy.Identifier {
+	return constraint.ids
+}
+
+func (constraint *AtLeastConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	return cardSort(lm, constraint.ids).Geq(constraint.n)
+}
+
+func (constraint *AtLeastConstraint) Order() []deppy.Identifier {
+	return nil
+}
+
+func (constraint *AtLeastConstraint) References() []deppy.Identifier {
+	return constraint.ids
+}
+
+func (constraint *AtLeastConstraint) Anchor() bool {
+	return false
+}
+
+// AtLeast returns a Constraint that forbids solutions that contain
+// fewer than n of the Variables identified by the given
+// Identifiers.
+func AtLeast(n int, ids ...deppy.Identifier) deppy.Constraint {
+	return &AtLeastConstraint{
+		ids: ids,
+		n:   n,
+	}
+}
+
+type ExactlyConstraint struct {
+	ids []deppy.Identifier
+	n   int
+}
+
+func (constraint *ExactlyConstraint) String(subject deppy.Identifier) string {
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *ExactlyConstraint) Message(subject deppy.Identifier) Message {
+	return Message{
+		ID:      "exactly",
+		Subject: subject,
+		Params:  map[string]interface{}{"N": constraint.n, "IDs": constraint.ids},
+	}
+}
+
+func (constraint *ExactlyConstraint) N() int {
+	return constraint.n
+}
+
+func (constraint *ExactlyConstraint) Ids() []deppy.Identifier {
+	return constraint.ids
+}
+
+func (constraint *ExactlyConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	cs := cardSort(lm, constraint.ids)
+	return lm.LogicCircuit().And(cs.Geq(constraint.n), cs.Leq(constraint.n))
+}
+
+func (constraint *ExactlyConstraint) Order() []deppy.Identifier {
+	return nil
+}
+
+func (constraint *ExactlyConstraint) References() []deppy.Identifier {
+	return constraint.ids
+}
+
+func (constraint *ExactlyConstraint) Anchor() bool {
+	return false
+}
+
+// Exactly returns a Constraint that permits only solutions that
+// contain exactly n of the Variables identified by the given
+// Identifiers.
+func Exactly(n int, ids ...deppy.Identifier) deppy.Constraint {
+	return &ExactlyConstraint{
+		ids: ids,
+		n:   n,
+	}
+}
+
+// ExactlyOne returns a Constraint that permits only solutions that
+// contain exactly one of the Variables identified by the given
+// Identifiers.
+func ExactlyOne(ids ...deppy.Identifier) deppy.Constraint {
+	return Exactly(1, ids...)
+}
+
+// ClauseLiteral is an occurrence of a Variable in a Clause, which is
+// satisfied when the Variable is selected, or when it is not selected
+// if Negated is true.
+type ClauseLiteral struct {
+	ID      deppy.Identifier
+	Negated bool
+}
+
+func (l ClauseLiteral) String() string {
+	if l.Negated {
+		return fmt.Sprintf("not %s", l.ID)
+	}
+	return string(l.ID)
+}
+
+// Positive returns a ClauseLiteral satisfied when the Variable
+// identified by id is selected.
+func Positive(id deppy.Identifier) ClauseLiteral {
+	return ClauseLiteral{ID: id}
+}
+
+// Negative returns a ClauseLiteral satisfied when the Variable
+// identified by id is not selected.
+func Negative(id deppy.Identifier) ClauseLiteral {
+	return ClauseLiteral{ID: id, Negated: true}
+}
+
+type ClauseConstraint struct {
+	literals []ClauseLiteral
+}
+
+func (constraint *ClauseConstraint) String(subject deppy.Identifier) string {
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *ClauseConstraint) Message(subject deppy.Identifier) Message {
+	return Message{
+		ID:      "clause",
+		Subject: subject,
+		Params:  map[string]interface{}{"Literals": constraint.literals},
+	}
+}
+
+func (constraint *ClauseConstraint) Literals() []ClauseLiteral {
+	return constraint.literals
+}
+
+func (constraint *ClauseConstraint) References() []deppy.Identifier {
+	var ids []deppy.Identifier
+	seen := map[deppy.Identifier]struct{}{}
+	for _, each := range constraint.literals {
+		if _, ok := seen[each.ID]; ok {
+			continue
+		}
+		seen[each.ID] = struct{}{}
+		ids = append(ids, each.ID)
+	}
+	return ids
+}
+
+func (constraint *ClauseConstraint) Apply(lm deppy.LitMapping, _ deppy.Identifier) z.Lit {
+	ms := make([]z.Lit, len(constraint.literals))
+	for i, each := range constraint.literals {
+		ms[i] = lm.LitOf(each.ID)
+		if each.Negated {
+			ms[i] = ms[i].Not()
+		}
+	}
+	return lm.LogicCircuit().Ors(ms...)
+}
+
+func (constraint *ClauseConstraint) Order() []deppy.Identifier {
+	return nil
+}
+
+func (constraint *ClauseConstraint) Anchor() bool {
+	return false
+}
+
+// Clause returns a Constraint that permits only solutions that satisfy
+// at least one of the given literals, independently of the Variable
+// it applies to. An empty clause permits no solution.
+func Clause(literals ...ClauseLiteral) deppy.Constraint {
+	return &ClauseConstraint{
+		literals: literals,
+	}
+}
+
 type OrConstraint struct {
 	operand          deppy.Identifier
 	isSubjectNegated bool
@@ -209,7 +625,31 @@ type OrConstraint struct {
 }
 
 func (constraint *OrConstraint) String(subject deppy.Identifier) string {
-	return fmt.Sprintf("%s is ProhibitedConstraint", subject)
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *OrConstraint) Message(subject deppy.Identifier) Message {
+	return Message{
+		ID:      "or",
+		Subject: subject,
+		Params: map[string]interface{}{
+			"Operand":        constraint.operand,
+			"SubjectNegated": constraint.isSubjectNegated,
+			"OperandNegated": constraint.isOperandNegated,
+		},
+	}
+}
+
+func (constraint *OrConstraint) Operand() deppy.Identifier {
+	return constraint.operand
+}
+
+func (constraint *OrConstraint) IsSubjectNegated() bool {
+	return constraint.isSubjectNegated
+}
+
+func (constraint *OrConstraint) IsOperandNegated() bool {
+	return constraint.isOperandNegated
 }
 
 func (constraint *OrConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
@@ -228,6 +668,10 @@ func (constraint *OrConstraint) Order() []deppy.Identifier {
 	return nil
 }
 
+func (constraint *OrConstraint) References() []deppy.Identifier {
+	return []deppy.Identifier{constraint.operand}
+}
+
 func (constraint *OrConstraint) Anchor() bool {
 	return false
 }
@@ -243,3 +687,13 @@ func Or(identifier deppy.Identifier, isSubjectNegated bool, isOperandNegated boo
 		isOperandNegated: isOperandNegated,
 	}
 }
+
+// cardSort returns a sorting network over the literals of the
+// Variables identified by ids, used to encode cardinality constraints.
+func cardSort(lm deppy.LitMapping, ids []deppy.Identifier) *logic.CardSort {
+	ms := make([]z.Lit, len(ids))
+	for i, each := range ids {
+		ms[i] = lm.LitOf(each)
+	}
+	return lm.LogicCircuit().CardSort(ms)
+}
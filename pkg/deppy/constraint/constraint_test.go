@@ -26,4 +26,171 @@ var _ = Describe("Constraint", func() {
 			Expect(userFriendlyConstraint.String("this thing")).To(Equal("'this thing' just _has_ to be there or you can't even..."))
 		})
 	})
+	Describe("Cardinality constraints", func() {
+		It("should describe AtLeast", func() {
+			Expect(constraint.AtLeast(2, "a", "b", "c").String("x")).To(Equal("x requires at least 2 of a, b, c"))
+		})
+		It("should describe Exactly", func() {
+			Expect(constraint.Exactly(2, "a", "b", "c").String("x")).To(Equal("x requires exactly 2 of a, b, c"))
+		})
+		It("should describe ExactlyOne", func() {
+			Expect(constraint.ExactlyOne("a", "b").String("x")).To(Equal("x requires exactly one of a, b"))
+		})
+	})
+	Describe("Combinators", func() {
+		It("should nest the messages of composed constraints", func() {
+			c := constraint.Implies(constraint.Mandatory(), constraint.Any(
+				constraint.Dependency("b"),
+				constraint.All(constraint.Dependency("c"), constraint.Negate(constraint.Dependency("d"))),
+			))
+			Expect(c.String("a")).To(Equal("if a is mandatory then (a requires at least one of b or (a requires at least one of c and not (a requires at least one of d)))"))
+		})
+		It("should describe Equiv", func() {
+			Expect(constraint.Equiv(constraint.Mandatory(), constraint.Dependency("b")).String("a")).To(Equal("a is mandatory if and only if a requires at least one of b"))
+		})
+		It("should merge the preferences of composed constraints", func() {
+			c := constraint.All(constraint.Dependency("x", "y"), constraint.Any(constraint.Dependency("y", "z")), constraint.Negate(constraint.Dependency("w")))
+			Expect(c.Order()).To(Equal([]deppy.Identifier{"x", "y", "z"}))
+		})
+		It("should keep the choices of each conjunct apart", func() {
+			c := constraint.All(constraint.Dependency("x", "y"), constraint.Negate(constraint.Dependency("w")), constraint.Dependency("y", "z"))
+			Expect(deppy.ChoicesOf(c)).To(Equal([][]deppy.Identifier{{"x", "y"}, {"y", "z"}}))
+			Expect(deppy.ChoicesOf(constraint.Implies(constraint.Mandatory(), c))).To(Equal([][]deppy.Identifier{{"x", "y"}, {"y", "z"}}))
+			Expect(deppy.ChoicesOf(constraint.WithMetadata(c, deppy.Metadata{Source: "user"}))).To(Equal([][]deppy.Identifier{{"x", "y"}, {"y", "z"}}))
+			Expect(deppy.ChoicesOf(constraint.Mandatory())).To(BeNil())
+			Expect(deppy.ChoicesOf(constraint.Implies(constraint.Selected("w"), c))).To(BeNil())
+			Expect(deppy.ChoicesOf(constraint.Equiv(c, constraint.WithSource(constraint.Mandatory(), "user")))).To(Equal([][]deppy.Identifier{{"x", "y"}, {"y", "z"}}))
+			Expect(deppy.ChoicesOf(constraint.Equiv(constraint.Selected("w"), c))).To(BeNil())
+		})
+		It("should anchor when a conjunct anchors", func() {
+			Expect(constraint.All(constraint.Dependency("x"), constraint.Mandatory()).Anchor()).To(BeTrue())
+			Expect(constraint.Any(constraint.Dependency("x"), constraint.Mandatory()).Anchor()).To(BeFalse())
+			Expect(constraint.Negate(constraint.Mandatory()).Anchor()).To(BeFalse())
+		})
+	})
+	Describe("Weighted sum constraints", func() {
+		It("should describe the weighted terms in a stable order", func() {
+			weights := map[deppy.Identifier]int{"c": 1, "a": 3, "b": 2}
+			Expect(constraint.WeightedAtMost(4, weights).String("x")).To(Equal("x permits a total weight of at most 4 from a (3), b (2), c (1)"))
+			Expect(constraint.WeightedAtLeast(4, weights).String("x")).To(Equal("x requires a total weight of at least 4 from a (3), b (2), c (1)"))
+			Expect(constraint.WeightedExactly(4, weights).String("x")).To(Equal("x requires a total weight of exactly 4 from a (3), b (2), c (1)"))
+		})
+	})
+	Describe("ClauseConstraint", func() {
+		It("should describe the literals of the clause", func() {
+			Expect(constraint.Clause(constraint.Positive("a"), constraint.Negative("b"), constraint.Positive("c")).String("x")).To(Equal("a or not b or c must hold"))
+			Expect(constraint.Clause().String("x")).To(Equal("empty clause cannot be satisfied"))
+		})
+	})
+	Describe("Equivalence and exclusive or", func() {
+		It("should describe Iff", func() {
+			Expect(constraint.Iff("b").String("a")).To(Equal("a must be selected together with b or not at all"))
+		})
+		It("should describe Xor", func() {
+			Expect(constraint.Xor("b").String("a")).To(Equal("either a or b must be selected, but not both"))
+		})
+	})
+	Describe("Metadata", func() {
+		It("should keep the message of the annotated constraint", func() {
+			Expect(constraint.WithSource(constraint.Mandatory(), "user").String("a")).To(Equal("a is mandatory"))
+		})
+		It("should merge metadata when annotating twice", func() {
+			c := constraint.WithMetadata(constraint.Mandatory(), deppy.Metadata{Source: "user", Labels: map[string]string{"a": "1"}})
+			c = constraint.WithMetadata(c, deppy.Metadata{Reason: "requested", Labels: map[string]string{"b": "2"}})
+			Expect(c.Constraint).To(Equal(constraint.Mandatory()))
+			Expect(deppy.MetadataOf(c)).To(Equal(deppy.Metadata{
+				Source: "user",
+				Reason: "requested",
+				Labels: map[string]string{"a": "1", "b": "2"},
+			}))
+		})
+		It("should report no metadata for plain constraints", func() {
+			Expect(deppy.MetadataOf(constraint.Mandatory()).IsZero()).To(BeTrue())
+		})
+	})
+	Describe("Messages", func() {
+		It("should describe Prohibited", func() {
+			Expect(constraint.Prohibited().String("a")).To(Equal("a is prohibited"))
+		})
+		It("should describe Or", func() {
+			Expect(constraint.Or("b", false, false).String("a")).To(Equal("a is selected or b is selected"))
+			Expect(constraint.Or("b", true, true).String("a")).To(Equal("a is not selected or b is not selected"))
+		})
+		It("should describe a Dependency without candidates", func() {
+			Expect(constraint.Dependency().String("a")).To(Equal("a has a dependency without any candidates to satisfy it"))
+		})
+		It("should expose structured message parameters", func() {
+			Expect(constraint.AtMost(1, "b", "c").(constraint.Describer).Message("a")).To(Equal(constraint.Message{
+				ID:      "atMost",
+				Subject: "a",
+				Params:  map[string]interface{}{"N": 1, "IDs": []deppy.Identifier{"b", "c"}},
+			}))
+		})
+		It("should render messages with a custom catalog", func() {
+			catalog, err := constraint.NewCatalog("de", map[string]string{
+				"mandatory":  `{{.Subject}} ist erforderlich`,
+				"dependency": `{{.Subject}} benötigt eines von {{join .IDs " oder "}}`,
+				"all":        `{{range $i, $c := .Constraints}}{{if $i}} und {{end}}{{nested $c}}{{end}}`,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			c := constraint.All(constraint.Mandatory(), constraint.Dependency("b", "c"), constraint.Any(constraint.Prohibited()))
+			// messages without a German template fall back to English
+			Expect(catalog.Format(c, "a")).To(Equal("a ist erforderlich und a benötigt eines von b oder c und (a is prohibited)"))
+			Expect(catalog.Format(constraint.Any(constraint.Mandatory(), constraint.Prohibited()), "a")).To(Equal("a ist erforderlich or a is prohibited"))
+		})
+		It("should render user friendly constraints with a catalog", func() {
+			catalog, err := constraint.NewCatalog("fr", map[string]string{
+				"conflict": `{{.Subject}} est en conflit avec {{.ID}}`,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			c := constraint.NewUserFriendlyConstraint(constraint.WithSource(constraint.Conflict("b"), "user"), catalog.Formatter())
+			Expect(c.String("a")).To(Equal("a est en conflit avec b"))
+		})
+		It("should reject invalid templates", func() {
+			_, err := constraint.NewCatalog("xx", map[string]string{"mandatory": `{{.Subject`})
+			Expect(err).To(HaveOccurred())
+		})
+		It("should fail to render messages without a template", func() {
+			catalog, err := constraint.NewCatalog("xx", nil)
+			Expect(err).ToNot(HaveOccurred())
+			_, err = catalog.Render(constraint.Message{ID: "unknown", Subject: "a"})
+			Expect(err).To(HaveOccurred())
+		})
+		It("should look up catalogs by locale", func() {
+			catalog, err := constraint.NewCatalog("pt", map[string]string{"mandatory": `{{.Subject}} é obrigatório`})
+			Expect(err).ToNot(HaveOccurred())
+			constraint.RegisterCatalog(catalog)
+			Expect(constraint.CatalogFor("pt")).To(BeIdenticalTo(catalog))
+			Expect(constraint.CatalogFor("pt-BR")).To(BeIdenticalTo(catalog))
+			Expect(constraint.CatalogFor("ja")).To(BeIdenticalTo(constraint.DefaultCatalog))
+			Expect(constraint.CatalogFor("pt-BR").Format(constraint.Mandatory(), "a")).To(Equal("a é obrigatório"))
+		})
+	})
+	DescribeTable("References",
+		func(c deppy.Constraint, expected []deppy.Identifier) {
+			_, ok := c.(deppy.Referrer)
+			Expect(ok).To(BeTrue())
+			Expect(deppy.ReferencesOf(c)).To(Equal(expected))
+		},
+		Entry("mandatory", constraint.Mandatory(), nil),
+		Entry("prohibited", constraint.Prohibited(), nil),
+		Entry("dependency", constraint.Dependency("a", "b"), []deppy.Identifier{"a", "b"}),
+		Entry("conflict", constraint.Conflict("a"), []deppy.Identifier{"a"}),
+		Entry("iff", constraint.Iff("a"), []deppy.Identifier{"a"}),
+		Entry("xor", constraint.Xor("a"), []deppy.Identifier{"a"}),
+		Entry("at most", constraint.AtMost(1, "a", "b"), []deppy.Identifier{"a", "b"}),
+		Entry("at least", constraint.AtLeast(1, "a", "b"), []deppy.Identifier{"a", "b"}),
+		Entry("exactly", constraint.Exactly(1, "a", "b"), []deppy.Identifier{"a", "b"}),
+		Entry("or", constraint.Or("a", true, false), []deppy.Identifier{"a"}),
+		Entry("selected", constraint.Selected("a"), []deppy.Identifier{"a"}),
+		Entry("clause", constraint.Clause(constraint.Positive("a"), constraint.Negative("b"), constraint.Negative("a")), []deppy.Identifier{"a", "b"}),
+		Entry("weighted sum", constraint.WeightedAtMost(2, map[deppy.Identifier]int{"b": 1, "a": 2}), []deppy.Identifier{"a", "b"}),
+		Entry("all", constraint.All(constraint.Conflict("a"), constraint.AtMost(1, "a", "b")), []deppy.Identifier{"a", "b"}),
+		Entry("any", constraint.Any(constraint.Mandatory(), constraint.Conflict("a")), []deppy.Identifier{"a"}),
+		Entry("negate", constraint.Negate(constraint.Conflict("a")), []deppy.Identifier{"a"}),
+		Entry("implies", constraint.Implies(constraint.Selected("a"), constraint.Dependency("b")), []deppy.Identifier{"a", "b"}),
+		Entry("equiv", constraint.Equiv(constraint.Selected("a"), constraint.Xor("b")), []deppy.Identifier{"a", "b"}),
+		Entry("annotated", constraint.WithSource(constraint.Conflict("a"), "user"), []deppy.Identifier{"a"}),
+		Entry("user friendly", constraint.NewUserFriendlyConstraint(constraint.Conflict("a"), nil), []deppy.Identifier{"a"}),
+	)
 })
@@ -0,0 +1,560 @@
+package constraint
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/go-air/gini/z"
+
+	"github.com/operator-framework/deppy/internal/parse"
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+// SelectedConstraint is satisfied by solutions that contain a
+// particular Variable, independently of the subject it applies to. It
+// is what bare identifiers in constraint expressions compile to.
+type SelectedConstraint struct {
+	id deppy.Identifier
+}
+
+func (constraint *SelectedConstraint) String(subject deppy.Identifier) string {
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *SelectedConstraint) Message(subject deppy.Identifier) Message {
+	return Message{
+		ID:      "selected",
+		Subject: subject,
+		Params:  map[string]interface{}{"ID": constraint.id},
+	}
+}
+
+func (constraint *SelectedConstraint) Apply(lm deppy.LitMapping, _ deppy.Identifier) z.Lit {
+	return lm.LitOf(constraint.id)
+}
+
+func (constraint *SelectedConstraint) Identifier() deppy.Identifier {
+	return constraint.id
+}
+
+func (constraint *SelectedConstraint) Order() []deppy.Identifier {
+	return []deppy.Identifier{constraint.id}
+}
+
+func (constraint *SelectedConstraint) References() []deppy.Identifier {
+	return []deppy.Identifier{constraint.id}
+}
+
+func (constraint *SelectedConstraint) Anchor() bool {
+	return false
+}
+
+// Selected returns a Constraint that will permit only solutions that
+// contain the Variable identified by the given Identifier.
+func Selected(id deppy.Identifier) deppy.Constraint {
+	return &SelectedConstraint{
+		id: id,
+	}
+}
+
+// Expression is the syntax tree of a boolean constraint expression, as
+// returned by ParseExpression. String returns a canonical form of the
+// expression which parses back into an equivalent tree.
+type Expression interface {
+	String() string
+	// Constraint compiles the expression into a Constraint.
+	Constraint() deppy.Constraint
+	precedence() int
+}
+
+// Operator precedences, from loosest to tightest binding.
+const (
+	precedenceIff = iota
+	precedenceImplies
+	precedenceOr
+	precedenceAnd
+	precedenceUnary
+)
+
+// BinaryExpression applies a binary operator ("&&", "||", "->" or
+// "<->") to two operands.
+type BinaryExpression struct {
+	Operator string
+	Left     Expression
+	Right    Expression
+}
+
+func (e *BinaryExpression) precedence() int {
+	switch e.Operator {
+	case "&&":
+		return precedenceAnd
+	case "||":
+		return precedenceOr
+	case "->":
+		return precedenceImplies
+	}
+	return precedenceIff
+}
+
+func (e *BinaryExpression) String() string {
+	left, right := e.Left.String(), e.Right.String()
+	// "&&" and "||" are associative and parsed left to right, while
+	// "->" and "<->" are right-associative
+	leftAssociative := e.Operator == "&&" || e.Operator == "||"
+	if p := e.Left.precedence(); p < e.precedence() || (p == e.precedence() && !leftAssociative) {
+		left = "(" + left + ")"
+	}
+	if p := e.Right.precedence(); p < e.precedence() || (p == e.precedence() && leftAssociative) {
+		right = "(" + right + ")"
+	}
+	return fmt.Sprintf("%s %s %s", left, e.Operator, right)
+}
+
+func (e *BinaryExpression) Constraint() deppy.Constraint {
+	switch e.Operator {
+	case "&&":
+		return All(e.flatten(nil)...)
+	case "||":
+		return Any(e.flatten(nil)...)
+	case "->":
+		return Implies(e.Left.Constraint(), e.Right.Constraint())
+	}
+	return Equiv(e.Left.Constraint(), e.Right.Constraint())
+}
+
+// flatten collects the operands of a chain of the same associative
+// operator, so that "a && b && c" compiles into a single All.
+func (e *BinaryExpression) flatten(dst []deppy.Constraint) []deppy.Constraint {
+	for _, operand := range []Expression{e.Left, e.Right} {
+		if b, ok := operand.(*BinaryExpression); ok && b.Operator == e.Operator {
+			dst = b.flatten(dst)
+			continue
+		}
+		dst = append(dst, operand.Constraint())
+	}
+	return dst
+}
+
+// NotExpression negates its operand.
+type NotExpression struct {
+	Operand Expression
+}
+
+func (e *NotExpression) precedence() int {
+	return precedenceUnary
+}
+
+func (e *NotExpression) String() string {
+	if e.Operand.precedence() < precedenceUnary {
+		return "!(" + e.Operand.String() + ")"
+	}
+	return "!" + e.Operand.String()
+}
+
+func (e *NotExpression) Constraint() deppy.Constraint {
+	return Negate(e.Operand.Constraint())
+}
+
+// IdentifierExpression is satisfied when the Variable it names is
+// part of the solution.
+type IdentifierExpression struct {
+	ID deppy.Identifier
+}
+
+func (e *IdentifierExpression) precedence() int {
+	return precedenceUnary
+}
+
+func (e *IdentifierExpression) String() string {
+	return formatIdentifier(e.ID)
+}
+
+func (e *IdentifierExpression) Constraint() deppy.Constraint {
+	return Selected(e.ID)
+}
+
+// CallExpression invokes one of the built-in constraint functions,
+// e.g. "requires(a, b)" or "atMost(1, a, b, c)".
+type CallExpression struct {
+	Function string
+	// N is the cardinality argument of functions that take one.
+	N   int
+	IDs []deppy.Identifier
+}
+
+func (e *CallExpression) precedence() int {
+	return precedenceUnary
+}
+
+func (e *CallExpression) String() string {
+	var args []string
+	if expressionFunctions[e.Function].cardinality {
+		args = append(args, strconv.Itoa(e.N))
+	}
+	for _, id := range e.IDs {
+		args = append(args, formatIdentifier(id))
+	}
+	return fmt.Sprintf("%s(%s)", e.Function, strings.Join(args, ", "))
+}
+
+func (e *CallExpression) Constraint() deppy.Constraint {
+	return expressionFunctions[e.Function].build(e.N, e.IDs)
+}
+
+type expressionFunction struct {
+	// cardinality is true if the first argument is an integer
+	cardinality bool
+	// minIDs and maxIDs bound the number of identifier
+	// arguments, maxIDs < 0 meaning unbounded
+	minIDs, maxIDs int
+	build          func(n int, ids []deppy.Identifier) deppy.Constraint
+}
+
+var expressionFunctions = map[string]expressionFunction{
+	"mandatory": {build: func(int, []deppy.Identifier) deppy.Constraint {
+		return Mandatory()
+	}},
+	"prohibited": {build: func(int, []deppy.Identifier) deppy.Constraint {
+		return Prohibited()
+	}},
+	"requires": {minIDs: 1, maxIDs: -1, build: func(_ int, ids []deppy.Identifier) deppy.Constraint {
+		return Dependency(ids...)
+	}},
+	"conflicts": {minIDs: 1, maxIDs: 1, build: func(_ int, ids []deppy.Identifier) deppy.Constraint {
+		return Conflict(ids[0])
+	}},
+	"iff": {minIDs: 1, maxIDs: 1, build: func(_ int, ids []deppy.Identifier) deppy.Constraint {
+		return Iff(ids[0])
+	}},
+	"xor": {minIDs: 1, maxIDs: 1, build: func(_ int, ids []deppy.Identifier) deppy.Constraint {
+		return Xor(ids[0])
+	}},
+	"atMost": {cardinality: true, maxIDs: -1, build: func(n int, ids []deppy.Identifier) deppy.Constraint {
+		return AtMost(n, ids...)
+	}},
+	"atLeast": {cardinality: true, maxIDs: -1, build: func(n int, ids []deppy.Identifier) deppy.Constraint {
+		return AtLeast(n, ids...)
+	}},
+	"exactly": {cardinality: true, maxIDs: -1, build: func(n int, ids []deppy.Identifier) deppy.Constraint {
+		return Exactly(n, ids...)
+	}},
+	"exactlyOne": {maxIDs: -1, build: func(_ int, ids []deppy.Identifier) deppy.Constraint {
+		return ExactlyOne(ids...)
+	}},
+}
+
+// SyntaxError describes a malformed constraint expression. Offset is
+// the byte offset of the offending input, Line and Column its
+// one-based position.
+type SyntaxError = parse.SyntaxError
+
+// ParseExpression parses a boolean constraint expression such as
+// `requires(etcd) && !conflicts(foo) || atMost(1, a, b, c)` or
+// `a & (b | !c)`.
+//
+// Bare identifiers are satisfied when the Variable they name is
+// selected, and may be quoted to include arbitrary characters. The
+// available functions are mandatory(), prohibited(), requires(ids...),
+// conflicts(id), iff(id), xor(id), atMost(n, ids...),
+// atLeast(n, ids...), exactly(n, ids...) and exactlyOne(ids...). Operators, from tightest
+// to loosest binding, are "!", "&&" (or "&"), "||" (or "|"), "->"
+// and "<->".
+func ParseExpression(expression string) (Expression, error) {
+	p := &expressionParser{lexer: expressionLexer{parse.Scanner{Input: expression}}}
+	p.next()
+	e, err := p.parseIff()
+	if err != nil {
+		return nil, err
+	}
+	if p.err != nil || p.token.kind != tokenEOF {
+		return nil, p.errorf(p.token, "unexpected %s", p.token)
+	}
+	return e, nil
+}
+
+// CompileExpression parses a boolean constraint expression and
+// compiles it into a Constraint. See ParseExpression for the syntax.
+func CompileExpression(expression string) (deppy.Constraint, error) {
+	e, err := ParseExpression(expression)
+	if err != nil {
+		return nil, err
+	}
+	return e.Constraint(), nil
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdentifier
+	tokenString
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+	tokenAnd
+	tokenOr
+	tokenNot
+	tokenImplies
+	tokenIff
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	offset int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenString:
+		return fmt.Sprintf("string %s", t.text)
+	case tokenIdentifier:
+		return fmt.Sprintf("identifier %q", t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+type expressionLexer struct {
+	parse.Scanner
+}
+
+func isIdentifierRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.-/:@+~", r)
+}
+
+func (l *expressionLexer) next() (token, error) {
+	if !l.SkipSpace() {
+		return token{kind: tokenEOF, offset: l.Offset}, nil
+	}
+	start := l.Offset
+	rest := l.Rest()
+	for _, op := range []struct {
+		text string
+		kind tokenKind
+	}{
+		{"<->", tokenIff},
+		{"->", tokenImplies},
+		{"&&", tokenAnd},
+		{"||", tokenOr},
+		{"&", tokenAnd},
+		{"|", tokenOr},
+		{"!", tokenNot},
+		{"(", tokenLeftParen},
+		{")", tokenRightParen},
+		{",", tokenComma},
+	} {
+		if strings.HasPrefix(rest, op.text) {
+			l.Offset += len(op.text)
+			return token{kind: op.kind, text: op.text, offset: start}, nil
+		}
+	}
+
+	if rest[0] == '"' {
+		quoted, err := l.Quoted()
+		if err != nil {
+			return token{}, err
+		}
+		return token{kind: tokenString, text: quoted, offset: start}, nil
+	}
+
+	for l.Offset < len(l.Input) {
+		r, size := utf8.DecodeRuneInString(l.Input[l.Offset:])
+		// "-" is part of identifiers, but not when it starts "->"
+		if !isIdentifierRune(r) || strings.HasPrefix(l.Input[l.Offset:], "->") {
+			break
+		}
+		l.Offset += size
+	}
+	if l.Offset == start {
+		r, _ := utf8.DecodeRuneInString(rest)
+		return token{}, l.Errorf(start, "unexpected character %q", r)
+	}
+	return token{kind: tokenIdentifier, text: l.Input[start:l.Offset], offset: start}, nil
+}
+
+type expressionParser struct {
+	lexer expressionLexer
+	token token
+	err   error
+}
+
+func (p *expressionParser) next() {
+	if p.err != nil {
+		return
+	}
+	p.token, p.err = p.lexer.next()
+	if p.err != nil {
+		p.token = token{kind: tokenEOF, offset: p.err.(*SyntaxError).Offset}
+	}
+}
+
+func (p *expressionParser) errorf(at token, format string, args ...interface{}) error {
+	if p.err != nil {
+		return p.err
+	}
+	return p.lexer.Errorf(at.offset, format, args...)
+}
+
+func (p *expressionParser) expect(kind tokenKind, what string) (token, error) {
+	t := p.token
+	if t.kind != kind || p.err != nil {
+		return t, p.errorf(t, "expected %s, found %s", what, t)
+	}
+	p.next()
+	return t, nil
+}
+
+// parseIff and parseImplies parse right-associative operators, while
+// parseBinary parses the left-associative "||" and "&&".
+func (p *expressionParser) parseIff() (Expression, error) {
+	left, err := p.parseImplies()
+	if err != nil || p.token.kind != tokenIff {
+		return left, err
+	}
+	p.next()
+	right, err := p.parseIff()
+	if err != nil {
+		return nil, err
+	}
+	return &BinaryExpression{Operator: "<->", Left: left, Right: right}, nil
+}
+
+func (p *expressionParser) parseImplies() (Expression, error) {
+	left, err := p.parseBinary(precedenceOr)
+	if err != nil || p.token.kind != tokenImplies {
+		return left, err
+	}
+	p.next()
+	right, err := p.parseImplies()
+	if err != nil {
+		return nil, err
+	}
+	return &BinaryExpression{Operator: "->", Left: left, Right: right}, nil
+}
+
+func (p *expressionParser) parseBinary(precedence int) (Expression, error) {
+	operand := p.parseUnary
+	kind, operator := tokenAnd, "&&"
+	if precedence == precedenceOr {
+		operand = func() (Expression, error) { return p.parseBinary(precedenceAnd) }
+		kind, operator = tokenOr, "||"
+	}
+	left, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.token.kind == kind {
+		p.next()
+		right, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpression{Operator: operator, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseUnary() (Expression, error) {
+	t := p.token
+	switch t.kind {
+	case tokenNot:
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &NotExpression{Operand: operand}, nil
+	case tokenLeftParen:
+		p.next()
+		e, err := p.parseIff()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(tokenRightParen, `")"`); err != nil {
+			return nil, err
+		}
+		return e, nil
+	case tokenString:
+		p.next()
+		id, _ := strconv.Unquote(t.text)
+		return &IdentifierExpression{ID: deppy.Identifier(id)}, nil
+	case tokenIdentifier:
+		p.next()
+		if p.token.kind == tokenLeftParen {
+			return p.parseCall(t)
+		}
+		return &IdentifierExpression{ID: deppy.Identifier(t.text)}, nil
+	}
+	return nil, p.errorf(t, "expected identifier, function call, \"!\" or \"(\", found %s", t)
+}
+
+func (p *expressionParser) parseCall(name token) (Expression, error) {
+	fn, ok := expressionFunctions[name.text]
+	if !ok {
+		return nil, p.errorf(name, "unknown function %q", name.text)
+	}
+	call := &CallExpression{Function: name.text}
+	p.next() // consume "("
+
+	var args []token
+	for p.token.kind != tokenRightParen {
+		if len(args) > 0 {
+			if _, err := p.expect(tokenComma, `"," or ")"`); err != nil {
+				return nil, err
+			}
+		}
+		t := p.token
+		if t.kind != tokenIdentifier && t.kind != tokenString || p.err != nil {
+			return nil, p.errorf(t, "expected argument, found %s", t)
+		}
+		args = append(args, t)
+		p.next()
+	}
+	closing := p.token
+	p.next()
+
+	if fn.cardinality {
+		if len(args) == 0 {
+			return nil, p.errorf(closing, "%s requires a cardinality argument", name.text)
+		}
+		n, err := strconv.Atoi(args[0].text)
+		if err != nil || args[0].kind != tokenIdentifier || n < 0 {
+			return nil, p.errorf(args[0], "expected non-negative integer, found %s", args[0])
+		}
+		call.N = n
+		args = args[1:]
+	}
+	if len(args) < fn.minIDs {
+		return nil, p.errorf(closing, "%s requires at least %d identifier argument(s)", name.text, fn.minIDs)
+	}
+	if fn.maxIDs >= 0 && len(args) > fn.maxIDs {
+		return nil, p.errorf(args[fn.maxIDs], "%s accepts at most %d identifier argument(s)", name.text, fn.maxIDs)
+	}
+	for _, arg := range args {
+		id := arg.text
+		if arg.kind == tokenString {
+			id, _ = strconv.Unquote(arg.text)
+		}
+		call.IDs = append(call.IDs, deppy.Identifier(id))
+	}
+	return call, nil
+}
+
+// formatIdentifier returns id as it should appear in an expression,
+// quoting it unless it would be read back as the same bare identifier.
+func formatIdentifier(id deppy.Identifier) string {
+	s := string(id)
+	bare := s != "" && !strings.Contains(s, "->")
+	for _, r := range s {
+		bare = bare && isIdentifierRune(r)
+	}
+	if bare {
+		return s
+	}
+	return strconv.Quote(s)
+}
@@ -0,0 +1,80 @@
+package constraint_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint"
+)
+
+var _ = Describe("Expression", func() {
+	DescribeTable("should compile expressions into constraints",
+		func(expression string, expected deppy.Constraint) {
+			c, err := constraint.CompileExpression(expression)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(c).To(Equal(expected))
+		},
+		Entry("identifiers", "a & (b | !c)", constraint.All(
+			constraint.Selected("a"),
+			constraint.Any(constraint.Selected("b"), constraint.Negate(constraint.Selected("c"))),
+		)),
+		Entry("functions", "requires(etcd) && !conflicts(foo) || atMost(1, a, b, c)", constraint.Any(
+			constraint.All(constraint.Dependency("etcd"), constraint.Negate(constraint.Conflict("foo"))),
+			constraint.AtMost(1, "a", "b", "c"),
+		)),
+		Entry("flattened chains", "a && b && c", constraint.All(constraint.Selected("a"), constraint.Selected("b"), constraint.Selected("c"))),
+		Entry("implications", "mandatory() -> exactlyOne(a, b) <-> atLeast(2, c, d)", constraint.Equiv(
+			constraint.Implies(constraint.Mandatory(), constraint.ExactlyOne("a", "b")),
+			constraint.AtLeast(2, "c", "d"),
+		)),
+		Entry("quoted identifiers", `requires("my bundle", b-1.0.0) -> prohibited()`, constraint.Implies(
+			constraint.Dependency("my bundle", "b-1.0.0"),
+			constraint.Prohibited(),
+		)),
+		Entry("pairwise functions", "iff(a) || xor(b)", constraint.Any(constraint.Iff("a"), constraint.Xor("b"))),
+	)
+
+	DescribeTable("should round-trip through String",
+		func(expression string, canonical string) {
+			e, err := constraint.ParseExpression(expression)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(e.String()).To(Equal(canonical))
+			again, err := constraint.ParseExpression(e.String())
+			Expect(err).ToNot(HaveOccurred())
+			Expect(again).To(Equal(e))
+		},
+		Entry("precedence", "a & (b | !c)", "a && (b || !c)"),
+		Entry("redundant parentheses", "((a && b)) || (c)", "a && b || c"),
+		Entry("associativity", "(a -> b) -> (c -> d)", "(a -> b) -> c -> d"),
+		Entry("negated groups", "!(a || b) && !!c", "!(a || b) && !!c"),
+		Entry("functions", "atMost( 1,a ,\"b c\" )", `atMost(1, a, "b c")`),
+	)
+
+	DescribeTable("should report the position of syntax errors",
+		func(expression string, line, column int, msg string) {
+			_, err := constraint.ParseExpression(expression)
+			Expect(err).To(HaveOccurred())
+			var syntaxErr *constraint.SyntaxError
+			Expect(err).To(BeAssignableToTypeOf(syntaxErr))
+			syntaxErr = err.(*constraint.SyntaxError)
+			Expect(syntaxErr.Line).To(Equal(line))
+			Expect(syntaxErr.Column).To(Equal(column))
+			Expect(syntaxErr.Msg).To(Equal(msg))
+		},
+		Entry("missing operand", "a && ", 1, 6, `expected identifier, function call, "!" or "(", found end of expression`),
+		Entry("unbalanced parentheses", "(a || b", 1, 8, `expected ")", found end of expression`),
+		Entry("trailing input", "a b", 1, 3, `unexpected identifier "b"`),
+		Entry("unknown function", "a &&\n  needs(b)", 2, 3, `unknown function "needs"`),
+		Entry("invalid cardinality", "atMost(x, a)", 1, 8, `expected non-negative integer, found identifier "x"`),
+		Entry("too many arguments", "conflicts(a, b)", 1, 14, "conflicts accepts at most 1 identifier argument(s)"),
+		Entry("unexpected character", "a && $", 1, 6, `unexpected character '$'`),
+		Entry("unterminated string", `requires("a)`, 1, 10, "unterminated or malformed string"),
+	)
+
+	It("should describe compiled expressions", func() {
+		c, err := constraint.CompileExpression("a & (b | !c)")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(c.String("x")).To(Equal("a is selected and (b is selected or not (c is selected))"))
+	})
+})
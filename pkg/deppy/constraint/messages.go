@@ -0,0 +1,246 @@
+package constraint
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+// Message describes the message of a constraint independently of its
+// wording, so that it can be rendered by a Catalog.
+type Message struct {
+	// ID names the kind of message, e.g. "mandatory" or "atMost".
+	// Catalogs hold one template per ID.
+	ID string
+	// Subject is the Variable the constraint applies to.
+	Subject deppy.Identifier
+	// Params are the values the message refers to. Values that are
+	// Constraints, or slices of Constraints, are rendered by the
+	// Catalog before being passed to the template.
+	Params map[string]interface{}
+}
+
+// Describer is implemented by constraints whose message can be
+// rendered by a Catalog. Every built-in constraint implements it.
+type Describer interface {
+	Message(subject deppy.Identifier) Message
+}
+
+// Rendered is the message of a nested constraint, as passed to
+// templates in place of the constraint itself.
+type Rendered struct {
+	Text string
+	// Compound is true if the constraint is composed of other
+	// constraints, in which case the message should be set apart
+	// when embedded in another message.
+	Compound bool
+}
+
+func (r Rendered) String() string {
+	return r.Text
+}
+
+// WeightedTerm is a single term of the message of a weighted sum.
+type WeightedTerm struct {
+	ID     deppy.Identifier
+	Weight int
+}
+
+// Catalog renders constraint messages from text/template templates,
+// one per message ID. Templates are executed with the message
+// parameters and "Subject", and can use the functions "join", which
+// joins the elements of a slice with a separator, and "nested", which
+// parenthesizes the message of a compound nested constraint.
+type Catalog struct {
+	locale    string
+	templates map[string]*template.Template
+}
+
+// NewCatalog returns a Catalog for locale with the given templates,
+// keyed by message ID.
+func NewCatalog(locale string, templates map[string]string) (*Catalog, error) {
+	c := &Catalog{
+		locale:    locale,
+		templates: make(map[string]*template.Template, len(templates)),
+	}
+	for id, text := range templates {
+		t, err := template.New(id).Option("missingkey=error").Funcs(templateFuncs).Parse(text)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing template for message %q: %w", id, err)
+		}
+		c.templates[id] = t
+	}
+	return c, nil
+}
+
+// Locale returns the locale of the receiver.
+func (c *Catalog) Locale() string {
+	return c.locale
+}
+
+// Render returns the text of the given Message. Messages without a
+// template in the receiver are rendered with the template of the
+// DefaultCatalog; nested constraints are still rendered by the
+// receiver. It fails if neither has a template for the Message.
+func (c *Catalog) Render(m Message) (string, error) {
+	t, ok := c.templates[m.ID]
+	if !ok {
+		t, ok = DefaultCatalog.templates[m.ID]
+	}
+	if !ok {
+		return "", fmt.Errorf("no template for message %q in catalog %q", m.ID, c.locale)
+	}
+	data := make(map[string]interface{}, len(m.Params)+1)
+	for k, v := range m.Params {
+		switch v := v.(type) {
+		case deppy.Constraint:
+			data[k] = c.rendered(v, m.Subject)
+		case []deppy.Constraint:
+			rs := make([]Rendered, len(v))
+			for i, each := range v {
+				rs[i] = c.rendered(each, m.Subject)
+			}
+			data[k] = rs
+		default:
+			data[k] = v
+		}
+	}
+	data["Subject"] = m.Subject
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// Format returns the message of constraint applied to subject.
+// Constraints that don't implement Describer are described by their
+// own String method.
+func (c *Catalog) Format(constraint deppy.Constraint, subject deppy.Identifier) string {
+	if annotated, ok := constraint.(*AnnotatedConstraint); ok {
+		constraint = annotated.Constraint
+	}
+	describer, ok := constraint.(Describer)
+	if !ok {
+		return constraint.String(subject)
+	}
+	m := describer.Message(subject)
+	s, err := c.Render(m)
+	if err != nil {
+		return fmt.Sprintf("%s: %v", m.ID, err)
+	}
+	return s
+}
+
+// Formatter returns a UserFriendlyConstraintMessageFormatter that
+// renders the message of the wrapped constraint with the receiver.
+func (c *Catalog) Formatter() UserFriendlyConstraintMessageFormatter {
+	return func(constraint deppy.Constraint, subject deppy.Identifier) string {
+		if userFriendly, ok := constraint.(*UserFriendlyConstraint); ok {
+			constraint = userFriendly.Constraint
+		}
+		return c.Format(constraint, subject)
+	}
+}
+
+func (c *Catalog) rendered(constraint deppy.Constraint, subject deppy.Identifier) Rendered {
+	if annotated, ok := constraint.(*AnnotatedConstraint); ok {
+		constraint = annotated.Constraint
+	}
+	_, isComposite := constraint.(composite)
+	_, isNot := constraint.(*NegateConstraint)
+	return Rendered{
+		Text: c.Format(constraint, subject),
+		// negations already set their operand apart
+		Compound: isComposite && !isNot,
+	}
+}
+
+var templateFuncs = template.FuncMap{
+	"join": func(items interface{}, sep string) (string, error) {
+		v := reflect.ValueOf(items)
+		if v.Kind() != reflect.Slice {
+			return "", fmt.Errorf("join: expected a slice, got %T", items)
+		}
+		s := make([]string, v.Len())
+		for i := range s {
+			s[i] = fmt.Sprint(v.Index(i).Interface())
+		}
+		return strings.Join(s, sep), nil
+	},
+	"nested": func(r Rendered) string {
+		if r.Compound {
+			return fmt.Sprintf("(%s)", r.Text)
+		}
+		return r.Text
+	},
+}
+
+// DefaultCatalog renders the messages of every built-in constraint in
+// English. It is used by their String methods.
+var DefaultCatalog *Catalog
+
+var englishTemplates = map[string]string{
+	"mandatory":   `{{.Subject}} is mandatory`,
+	"prohibited":  `{{.Subject}} is prohibited`,
+	"dependency":  `{{if .IDs}}{{.Subject}} requires at least one of {{join .IDs ", "}}{{else}}{{.Subject}} has a dependency without any candidates to satisfy it{{end}}`,
+	"conflict":    `{{.Subject}} conflicts with {{.ID}}`,
+	"iff":         `{{.Subject}} must be selected together with {{.ID}} or not at all`,
+	"xor":         `either {{.Subject}} or {{.ID}} must be selected, but not both`,
+	"atMost":      `{{.Subject}} permits at most {{.N}} of {{join .IDs ", "}}`,
+	"atLeast":     `{{.Subject}} requires at least {{.N}} of {{join .IDs ", "}}`,
+	"exactly":     `{{.Subject}} requires exactly {{if eq .N 1}}one{{else}}{{.N}}{{end}} of {{join .IDs ", "}}`,
+	"or":          `{{.Subject}} is {{if .SubjectNegated}}not {{end}}selected or {{.Operand}} is {{if .OperandNegated}}not {{end}}selected`,
+	"selected":    `{{.ID}} is selected`,
+	"clause":      `{{if .Literals}}{{range $i, $l := .Literals}}{{if $i}} or {{end}}{{if $l.Negated}}not {{end}}{{$l.ID}}{{end}} must hold{{else}}empty clause cannot be satisfied{{end}}`,
+	"weightedSum": `{{.Subject}} {{if eq .Relation ">="}}requires a total weight of at least{{else if eq .Relation "="}}requires a total weight of exactly{{else}}permits a total weight of at most{{end}} {{.Limit}} from {{range $i, $t := .Terms}}{{if $i}}, {{end}}{{$t.ID}} ({{$t.Weight}}){{end}}`,
+	"all":         `{{range $i, $c := .Constraints}}{{if $i}} and {{end}}{{nested $c}}{{end}}`,
+	"any":         `{{range $i, $c := .Constraints}}{{if $i}} or {{end}}{{nested $c}}{{end}}`,
+	"negate":      `not ({{.Constraint}})`,
+	"implies":     `if {{nested .Antecedent}} then {{nested .Consequent}}`,
+	"equiv":       `{{nested .Left}} if and only if {{nested .Right}}`,
+}
+
+var (
+	catalogsMu sync.RWMutex
+	catalogs   = map[string]*Catalog{}
+)
+
+func init() {
+	c, err := NewCatalog("en", englishTemplates)
+	if err != nil {
+		panic(err)
+	}
+	DefaultCatalog = c
+	catalogs[c.locale] = c
+}
+
+// RegisterCatalog makes catalog available from CatalogFor under its
+// locale, replacing any Catalog previously registered for it.
+func RegisterCatalog(catalog *Catalog) {
+	catalogsMu.Lock()
+	defer catalogsMu.Unlock()
+	catalogs[catalog.locale] = catalog
+}
+
+// CatalogFor returns the Catalog registered for locale. If there is
+// none, it returns the Catalog registered for the language of locale
+// (e.g. "de" for "de-CH"), and failing that the DefaultCatalog.
+func CatalogFor(locale string) *Catalog {
+	catalogsMu.RLock()
+	defer catalogsMu.RUnlock()
+	if c, ok := catalogs[locale]; ok {
+		return c
+	}
+	if i := strings.IndexAny(locale, "-_"); i > 0 {
+		if c, ok := catalogs[locale[:i]]; ok {
+			return c
+		}
+	}
+	return DefaultCatalog
+}
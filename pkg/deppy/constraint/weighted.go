@@ -0,0 +1,208 @@
+package constraint
+
+import (
+	"sort"
+
+	"github.com/go-air/gini/logic"
+	"github.com/go-air/gini/z"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+type weightedRelation int
+
+const (
+	weightedAtMost weightedRelation = iota
+	weightedAtLeast
+	weightedExactly
+)
+
+type WeightedSumConstraint struct {
+	ids      []deppy.Identifier
+	weights  []int
+	limit    int
+	relation weightedRelation
+}
+
+func (constraint *WeightedSumConstraint) String(subject deppy.Identifier) string {
+	return DefaultCatalog.Format(constraint, subject)
+}
+
+func (constraint *WeightedSumConstraint) Message(subject deppy.Identifier) Message {
+	terms := make([]WeightedTerm, len(constraint.ids))
+	for i, each := range constraint.ids {
+		terms[i] = WeightedTerm{ID: each, Weight: constraint.weights[i]}
+	}
+	return Message{
+		ID:      "weightedSum",
+		Subject: subject,
+		Params: map[string]interface{}{
+			"Relation": constraint.Relation(),
+			"Limit":    constraint.limit,
+			"Terms":    terms,
+		},
+	}
+}
+
+// Limit returns the bound on the weighted sum.
+func (constraint *WeightedSumConstraint) Limit() int {
+	return constraint.limit
+}
+
+// Weights returns the weight of each Variable contributing to the sum.
+func (constraint *WeightedSumConstraint) Weights() map[deppy.Identifier]int {
+	weights := make(map[deppy.Identifier]int, len(constraint.ids))
+	for i, each := range constraint.ids {
+		weights[each] = constraint.weights[i]
+	}
+	return weights
+}
+
+// Relation returns the comparison between the weighted sum and the
+// limit: "<=", ">=" or "=".
+func (constraint *WeightedSumConstraint) Relation() string {
+	switch constraint.relation {
+	case weightedAtLeast:
+		return ">="
+	case weightedExactly:
+		return "="
+	}
+	return "<="
+}
+
+func (constraint *WeightedSumConstraint) Apply(lm deppy.LitMapping, subject deppy.Identifier) z.Lit {
+	c := lm.LogicCircuit()
+
+	// A negative weight w on m contributes w*m = |w|*¬m - |w|, so
+	// only non-negative weights need to be summed once the limit
+	// has been shifted accordingly.
+	limit := constraint.limit
+	var sum []z.Lit
+	for i, each := range constraint.ids {
+		m, w := lm.LitOf(each), constraint.weights[i]
+		if w < 0 {
+			m, w = m.Not(), -w
+			limit += w
+		}
+		sum = add(c, sum, weigh(c, m, w))
+	}
+
+	switch constraint.relation {
+	case weightedAtLeast:
+		return leq(c, sum, limit-1).Not()
+	case weightedExactly:
+		return c.And(leq(c, sum, limit), leq(c, sum, limit-1).Not())
+	}
+	return leq(c, sum, limit)
+}
+
+func (constraint *WeightedSumConstraint) Order() []deppy.Identifier {
+	return nil
+}
+
+func (constraint *WeightedSumConstraint) References() []deppy.Identifier {
+	return constraint.ids
+}
+
+func (constraint *WeightedSumConstraint) Anchor() bool {
+	return false
+}
+
+// WeightedAtMost returns a Constraint that forbids solutions in which
+// the weights of the selected Variables, among those identified by the
+// keys of weights, add up to more than limit.
+func WeightedAtMost(limit int, weights map[deppy.Identifier]int) deppy.Constraint {
+	return newWeightedSum(limit, weights, weightedAtMost)
+}
+
+// WeightedAtLeast returns a Constraint that forbids solutions in
+// which the weights of the selected Variables, among those identified
+// by the keys of weights, add up to less than limit.
+func WeightedAtLeast(limit int, weights map[deppy.Identifier]int) deppy.Constraint {
+	return newWeightedSum(limit, weights, weightedAtLeast)
+}
+
+// WeightedExactly returns a Constraint that permits only solutions in
+// which the weights of the selected Variables, among those identified
+// by the keys of weights, add up to exactly limit.
+func WeightedExactly(limit int, weights map[deppy.Identifier]int) deppy.Constraint {
+	return newWeightedSum(limit, weights, weightedExactly)
+}
+
+func newWeightedSum(limit int, weights map[deppy.Identifier]int, relation weightedRelation) *WeightedSumConstraint {
+	// keep terms in a stable order so that messages and circuits
+	// don't depend on map iteration
+	ids := make([]deppy.Identifier, 0, len(weights))
+	for id := range weights {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	ws := make([]int, len(ids))
+	for i, id := range ids {
+		ws[i] = weights[id]
+	}
+	return &WeightedSumConstraint{
+		ids:      ids,
+		weights:  ws,
+		limit:    limit,
+		relation: relation,
+	}
+}
+
+// weigh returns the little-endian binary representation of w if m is
+// true, and of zero otherwise.
+func weigh(c *logic.C, m z.Lit, w int) []z.Lit {
+	var bits []z.Lit
+	for ; w > 0; w >>= 1 {
+		if w&1 == 1 {
+			bits = append(bits, m)
+		} else {
+			bits = append(bits, c.F)
+		}
+	}
+	return bits
+}
+
+// add returns the little-endian binary sum of a and b, built out of
+// ripple-carry full adders.
+func add(c *logic.C, a, b []z.Lit) []z.Lit {
+	if len(a) < len(b) {
+		a, b = b, a
+	}
+	sum := make([]z.Lit, 0, len(a)+1)
+	carry := c.F
+	for i := range a {
+		y := c.F
+		if i < len(b) {
+			y = b[i]
+		}
+		x := c.Xor(a[i], y)
+		sum = append(sum, c.Xor(x, carry))
+		carry = c.Or(c.And(a[i], y), c.And(carry, x))
+	}
+	return append(sum, carry)
+}
+
+// leq returns a literal that is true if and only if the little-endian
+// binary number n is at most the constant k.
+func leq(c *logic.C, n []z.Lit, k int) z.Lit {
+	if k < 0 {
+		return c.F
+	}
+	if len(n) < 63 && k >= 1<<len(n) {
+		return c.T
+	}
+	// compare from the least significant bit up, so that each bit
+	// decides the result unless it is equal to the bit of k
+	m := c.T
+	for i, bit := range n {
+		if k>>i&1 == 1 {
+			m = c.Or(bit.Not(), m)
+		} else {
+			m = c.And(bit.Not(), m)
+		}
+	}
+	return m
+}
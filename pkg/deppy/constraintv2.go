@@ -0,0 +1,159 @@
+package deppy
+
+import (
+	"github.com/go-air/gini/z"
+)
+
+// Builder extends LitMapping for ConstraintV2 implementations. In
+// addition to the literals of Variables, it provides fresh auxiliary
+// literals and sub-circuits that can be shared between constraints.
+type Builder interface {
+	LitMapping
+	// Lookup returns the positive literal corresponding to the
+	// Variable with the given Identifier, or an error if there is
+	// no such Variable.
+	Lookup(id Identifier) (z.Lit, error)
+	// NewLit returns a fresh literal that doesn't correspond to any
+	// Variable.
+	NewLit() z.Lit
+	// Named returns the literal built under name. The first call
+	// for a given name invokes build and remembers its result, so
+	// that constraints can share a sub-circuit by agreeing on its
+	// name. Errors returned by build are not remembered.
+	Named(name string, build func() (z.Lit, error)) (z.Lit, error)
+	// Report records an error to be returned by the solver. It
+	// allows errors to be reported from contexts that cannot
+	// return them, such as the Apply method of a Constraint.
+	Report(err error)
+}
+
+// ConstraintV2 is like Constraint, except that Apply can report
+// errors and has access to a Builder. Use WrapV2 to attach a
+// ConstraintV2 to a Variable.
+type ConstraintV2 interface {
+	String(subject Identifier) string
+	// Apply returns the literal that is true if and only if the
+	// constraint is satisfied. It may return z.LitNull if the
+	// constraint has no useful representation in the SAT inputs.
+	Apply(b Builder, subject Identifier) (z.Lit, error)
+	Order() []Identifier
+	Anchor() bool
+}
+
+// WrappedV2 is a Constraint implemented by a ConstraintV2.
+type WrappedV2 struct {
+	ConstraintV2
+}
+
+// WrapV2 returns a Constraint implemented by the given ConstraintV2.
+// Solvers apply it through the ConstraintV2 interface with ApplyWith,
+// including when it is nested in a BuilderApplier such as the
+// combinators of package constraint. When applied through the
+// Constraint interface, by another Constraint for instance, any error
+// is reported to the LitMapping if it is a Builder and the constraint
+// is treated as having no representation. The returned Constraint
+// forwards the Metadata, References and Choices methods of the given
+// ConstraintV2, if it has them.
+func WrapV2(constraint ConstraintV2) *WrappedV2 {
+	return &WrappedV2{ConstraintV2: constraint}
+}
+
+// Metadata returns the Metadata of the wrapped ConstraintV2, if it has
+// a Metadata method, so that MetadataOf sees through the wrapper.
+func (w *WrappedV2) Metadata() Metadata {
+	if annotated, ok := w.ConstraintV2.(interface{ Metadata() Metadata }); ok {
+		return annotated.Metadata()
+	}
+	return Metadata{}
+}
+
+// References returns the references of the wrapped ConstraintV2 if it
+// implements Referrer, and its Order otherwise, as ReferencesOf does.
+func (w *WrappedV2) References() []Identifier {
+	if referrer, ok := w.ConstraintV2.(Referrer); ok {
+		return referrer.References()
+	}
+	return w.Order()
+}
+
+// Choices returns the choices of the wrapped ConstraintV2 if it
+// implements Chooser, and its Order otherwise, as ChoicesOf does.
+func (w *WrappedV2) Choices() [][]Identifier {
+	if chooser, ok := w.ConstraintV2.(Chooser); ok {
+		return chooser.Choices()
+	}
+	if order := w.Order(); len(order) > 0 {
+		return [][]Identifier{order}
+	}
+	return nil
+}
+
+func (w *WrappedV2) Apply(lm LitMapping, subject Identifier) z.Lit {
+	b, ok := lm.(Builder)
+	if !ok {
+		return z.LitNull
+	}
+	m, err := w.ConstraintV2.Apply(b, subject)
+	if err != nil {
+		b.Report(err)
+		return z.LitNull
+	}
+	return m
+}
+
+// BuilderApplier is implemented by Constraints built out of other
+// Constraints, such as combinators, that can apply them with a Builder
+// and return their errors rather than report them.
+type BuilderApplier interface {
+	ApplyWith(b Builder, subject Identifier) (z.Lit, error)
+}
+
+// ApplyWith applies constraint to subject with b: through the
+// ConstraintV2 interface if it was created by WrapV2, through
+// ApplyWith if it implements BuilderApplier, and through its Apply
+// method otherwise. Solvers use it to apply every Constraint, so that
+// the errors of ConstraintV2 implementations are returned wherever
+// they are nested.
+func ApplyWith(b Builder, constraint Constraint, subject Identifier) (z.Lit, error) {
+	switch c := constraint.(type) {
+	case *WrappedV2:
+		return c.ConstraintV2.Apply(b, subject)
+	case BuilderApplier:
+		return c.ApplyWith(b, subject)
+	}
+	return constraint.Apply(b, subject), nil
+}
+
+// AdaptedV1 is a ConstraintV2 implemented by a Constraint.
+type AdaptedV1 struct {
+	Constraint Constraint
+}
+
+func (a AdaptedV1) String(subject Identifier) string {
+	return a.Constraint.String(subject)
+}
+
+// Apply applies the adapted Constraint. It never returns an error;
+// errors encountered by the adapted Constraint are recorded by the
+// Builder as before.
+func (a AdaptedV1) Apply(b Builder, subject Identifier) (z.Lit, error) {
+	return a.Constraint.Apply(b, subject), nil
+}
+
+func (a AdaptedV1) Order() []Identifier {
+	return a.Constraint.Order()
+}
+
+func (a AdaptedV1) Anchor() bool {
+	return a.Constraint.Anchor()
+}
+
+// AsV2 returns the ConstraintV2 implementing constraint if it was
+// created by WrapV2, and constraint adapted to ConstraintV2
+// otherwise.
+func AsV2(constraint Constraint) ConstraintV2 {
+	if w, ok := constraint.(*WrappedV2); ok {
+		return w.ConstraintV2
+	}
+	return AdaptedV1{Constraint: constraint}
+}
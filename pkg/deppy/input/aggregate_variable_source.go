@@ -0,0 +1,126 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+// VariableSourceError is returned by AggregateVariableSource when one of
+// its sources fails.
+type VariableSourceError struct {
+	// Index is the position of the source in Sources.
+	Index  int
+	Source VariableSource
+	Err    error
+}
+
+func (e *VariableSourceError) Error() string {
+	return fmt.Sprintf("error getting variables from source %d (%T): %v", e.Index, e.Source, e.Err)
+}
+
+func (e *VariableSourceError) Unwrap() error {
+	return e.Err
+}
+
+var _ VariableSource = &AggregateVariableSource{}
+
+// AggregateVariableSource is a VariableSource returning the variables
+// of several VariableSources. The sources are run concurrently, and
+// must therefore be safe to run concurrently against the same
+// EntitySource, but the variables are always returned in the order of
+// the sources, each source's variables in the order it returned them.
+// Variables with the same Identifier are merged according to Merge,
+// which by default reports them as an error.
+type AggregateVariableSource struct {
+	Sources []VariableSource
+	// Parallelism is the maximum number of sources run at the same
+	// time. It defaults to GOMAXPROCS.
+	Parallelism int
+	// Merge determines how variables with the same Identifier are
+	// combined, in the order of the sources.
+	Merge MergePolicy
+}
+
+// NewAggregateVariableSource returns an AggregateVariableSource over
+// the given sources.
+func NewAggregateVariableSource(sources ...VariableSource) *AggregateVariableSource {
+	return &AggregateVariableSource{Sources: sources}
+}
+
+// GetVariables returns the variables of every source. If any source
+// fails, the sources that haven't completed yet are cancelled, and the
+// error of the first failing source in the order of Sources is
+// returned as a *VariableSourceError. So is a DuplicateVariable error,
+// annotated with the source of the second variable.
+func (a *AggregateVariableSource) GetVariables(ctx context.Context, entitySource EntitySource) ([]deppy.Variable, error) {
+	parallelism := a.Parallelism
+	if parallelism <= 0 {
+		parallelism = runtime.GOMAXPROCS(0)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	results := make([][]deppy.Variable, len(a.Sources))
+	errs := make([]error, len(a.Sources))
+	semaphore := make(chan struct{}, parallelism)
+	var wg sync.WaitGroup
+	for i, source := range a.Sources {
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+		}
+		// don't start any source once another one failed
+		if err := ctx.Err(); err != nil {
+			errs[i] = err
+			continue
+		}
+		wg.Add(1)
+		go func(i int, source VariableSource) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[i], errs[i] = source.GetVariables(ctx, entitySource)
+			if errs[i] != nil {
+				cancel()
+			}
+		}(i, source)
+	}
+	wg.Wait()
+
+	// Sources cancelled because of another failure report the error
+	// of the context; prefer reporting the failure itself.
+	var first *VariableSourceError
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		sourceErr := &VariableSourceError{Index: i, Source: a.Sources[i], Err: err}
+		if first == nil {
+			first = sourceErr
+		}
+		if ctx.Err() == nil || !errors.Is(err, ctx.Err()) {
+			return nil, sourceErr
+		}
+	}
+	if first != nil {
+		return nil, first
+	}
+
+	n := 0
+	for _, variables := range results {
+		n += len(variables)
+	}
+	merger := newVariableMerger(a.Merge, n)
+	for i, variables := range results {
+		for _, variable := range variables {
+			if err := merger.add(variable); err != nil {
+				return nil, &VariableSourceError{Index: i, Source: a.Sources[i], Err: err}
+			}
+		}
+	}
+	return merger.variables, nil
+}
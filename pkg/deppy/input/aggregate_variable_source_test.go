@@ -0,0 +1,150 @@
+package input_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+// variableSourceFunc is a VariableSource implemented by a function.
+type variableSourceFunc func(ctx context.Context, entitySource input.EntitySource) ([]deppy.Variable, error)
+
+func (f variableSourceFunc) GetVariables(ctx context.Context, entitySource input.EntitySource) ([]deppy.Variable, error) {
+	return f(ctx, entitySource)
+}
+
+// delayedVariables returns a VariableSource returning variables with
+// the given identifiers after delay.
+func delayedVariables(delay time.Duration, ids ...deppy.Identifier) input.VariableSource {
+	return variableSourceFunc(func(ctx context.Context, _ input.EntitySource) ([]deppy.Variable, error) {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+		variables := make([]deppy.Variable, 0, len(ids))
+		for _, id := range ids {
+			variables = append(variables, input.NewSimpleVariable(id))
+		}
+		return variables, nil
+	})
+}
+
+func variableIDs(variables []deppy.Variable) []deppy.Identifier {
+	ids := make([]deppy.Identifier, 0, len(variables))
+	for _, variable := range variables {
+		ids = append(ids, variable.Identifier())
+	}
+	return ids
+}
+
+var _ = Describe("AggregateVariableSource", func() {
+	var (
+		ctx          context.Context
+		entitySource input.EntitySource
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		entitySource = input.NewCacheQuerier(map[deppy.Identifier]input.Entity{})
+	})
+
+	It("should return the variables in the order of the sources", func() {
+		aggregate := input.NewAggregateVariableSource(
+			delayedVariables(30*time.Millisecond, "a", "b"),
+			delayedVariables(0, "c"),
+			delayedVariables(10*time.Millisecond),
+			delayedVariables(20*time.Millisecond, "d", "e"),
+		)
+		variables, err := aggregate.GetVariables(ctx, entitySource)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(variableIDs(variables)).To(Equal([]deppy.Identifier{"a", "b", "c", "d", "e"}))
+	})
+
+	It("should return no variables without sources", func() {
+		variables, err := input.NewAggregateVariableSource().GetVariables(ctx, entitySource)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(variables).To(BeEmpty())
+	})
+
+	It("should pass the entity source to every source", func() {
+		var seen []input.EntitySource
+		aggregate := input.NewAggregateVariableSource(
+			variableSourceFunc(func(_ context.Context, entitySource input.EntitySource) ([]deppy.Variable, error) {
+				seen = append(seen, entitySource)
+				return nil, nil
+			}),
+		)
+		_, err := aggregate.GetVariables(ctx, entitySource)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(seen).To(Equal([]input.EntitySource{entitySource}))
+	})
+
+	It("should run the sources concurrently, up to Parallelism at a time", func() {
+		var running, peak int32
+		source := variableSourceFunc(func(context.Context, input.EntitySource) ([]deppy.Variable, error) {
+			n := atomic.AddInt32(&running, 1)
+			for {
+				p := atomic.LoadInt32(&peak)
+				if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			atomic.AddInt32(&running, -1)
+			return nil, nil
+		})
+		aggregate := input.NewAggregateVariableSource(source, source, source, source, source, source)
+		aggregate.Parallelism = 2
+
+		_, err := aggregate.GetVariables(ctx, entitySource)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(atomic.LoadInt32(&peak)).To(Equal(int32(2)))
+	})
+
+	It("should annotate errors with the failing source and cancel the others", func() {
+		failure := errors.New("failure")
+		cancelled := make(chan struct{})
+		aggregate := input.NewAggregateVariableSource(
+			variableSourceFunc(func(ctx context.Context, _ input.EntitySource) ([]deppy.Variable, error) {
+				<-ctx.Done()
+				close(cancelled)
+				return nil, ctx.Err()
+			}),
+			variableSourceFunc(func(context.Context, input.EntitySource) ([]deppy.Variable, error) {
+				return nil, failure
+			}),
+		)
+		aggregate.Parallelism = 2
+
+		variables, err := aggregate.GetVariables(ctx, entitySource)
+		Expect(variables).To(BeNil())
+		Expect(err).To(MatchError(failure))
+		Expect(err).To(MatchError(ContainSubstring("error getting variables from source 1 (input_test.variableSourceFunc): failure")))
+		var sourceErr *input.VariableSourceError
+		Expect(errors.As(err, &sourceErr)).To(BeTrue())
+		Expect(sourceErr.Index).To(Equal(1))
+		Expect(cancelled).To(BeClosed())
+	})
+
+	It("should not start sources once the context is done", func() {
+		var started int32
+		source := variableSourceFunc(func(context.Context, input.EntitySource) ([]deppy.Variable, error) {
+			atomic.AddInt32(&started, 1)
+			return nil, errors.New("failure")
+		})
+		aggregate := input.NewAggregateVariableSource(source, source, source)
+		aggregate.Parallelism = 1
+
+		_, err := aggregate.GetVariables(ctx, entitySource)
+		Expect(err).To(MatchError(ContainSubstring("source 0")))
+		Expect(atomic.LoadInt32(&started)).To(Equal(int32(1)))
+	})
+})
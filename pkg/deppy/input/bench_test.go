@@ -0,0 +1,93 @@
+package input_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+// benchmarkEntities returns n entities spread over n/20 packages, each
+// with two channels.
+func benchmarkEntities(n int) map[deppy.Identifier]input.Entity {
+	entities := make(map[deppy.Identifier]input.Entity, n)
+	for i := 0; i < n; i++ {
+		pkg := fmt.Sprintf("package-%d", i/20)
+		id := deppy.IdentifierFromString(fmt.Sprintf("%s.v%d.0.0", pkg, i%20))
+		entities[id] = *input.NewEntity(id, map[string]string{
+			"olm.packageName": pkg,
+			"olm.channel":     []string{"stable", "fast"}[i%2],
+			"olm.version":     fmt.Sprintf("%d.0.0", i%20),
+		})
+	}
+	return entities
+}
+
+func byPackage(pkg string) input.Predicate {
+	return func(entity *input.Entity) bool {
+		return entity.Properties["olm.packageName"] == pkg
+	}
+}
+
+func byPackageName(entity *input.Entity) []string {
+	return []string{entity.Properties["olm.packageName"]}
+}
+
+func benchmarkSources(b *testing.B, n int, run func(b *testing.B, source input.EntitySource)) {
+	entities := benchmarkEntities(n)
+	cache := input.NewCacheQuerier(entities)
+	indexed := input.NewIndexedEntitySource(entities, "olm.packageName", "olm.channel")
+	b.Run("Cache", func(b *testing.B) {
+		run(b, cache)
+	})
+	b.Run("Indexed", func(b *testing.B) {
+		run(b, indexed)
+	})
+}
+
+func BenchmarkFilter10k(b *testing.B) {
+	benchmarkSources(b, 10000, func(b *testing.B, source input.EntitySource) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := source.Filter(context.Background(), byPackage("package-42")); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkLookup10k(b *testing.B) {
+	benchmarkSources(b, 10000, func(b *testing.B, source input.EntitySource) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var err error
+			if indexed, ok := source.(*input.IndexedEntitySource); ok {
+				_, err = indexed.Lookup(context.Background(), "olm.packageName", "package-42")
+			} else {
+				_, err = source.Filter(context.Background(), byPackage("package-42"))
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkGroupBy10k(b *testing.B) {
+	benchmarkSources(b, 10000, func(b *testing.B, source input.EntitySource) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			var err error
+			if indexed, ok := source.(*input.IndexedEntitySource); ok {
+				_, err = indexed.GroupByProperty(context.Background(), "olm.packageName")
+			} else {
+				_, err = source.GroupBy(context.Background(), byPackageName)
+			}
+			if err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
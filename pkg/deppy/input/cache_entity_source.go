@@ -13,6 +13,9 @@ type CacheEntitySource struct {
 	entities map[deppy.Identifier]Entity
 }
 
+// NewCacheQuerier returns a CacheEntitySource over entities. The map is
+// not copied and must not be modified afterwards; use a
+// MutableEntitySource for entities that change.
 func NewCacheQuerier(entities map[deppy.Identifier]Entity) *CacheEntitySource {
 	return &CacheEntitySource{
 		entities: entities,
@@ -26,9 +29,12 @@ func (c CacheEntitySource) Get(_ context.Context, id deppy.Identifier) *Entity {
 	return nil
 }
 
-func (c CacheEntitySource) Filter(_ context.Context, filter Predicate) (EntityList, error) {
+func (c CacheEntitySource) Filter(ctx context.Context, filter Predicate) (EntityList, error) {
 	resultSet := EntityList{}
 	for _, entity := range c.entities {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		if filter(&entity) {
 			resultSet = append(resultSet, entity)
 		}
@@ -36,9 +42,12 @@ func (c CacheEntitySource) Filter(_ context.Context, filter Predicate) (EntityLi
 	return resultSet, nil
 }
 
-func (c CacheEntitySource) GroupBy(_ context.Context, fn GroupByFunction) (EntityListMap, error) {
+func (c CacheEntitySource) GroupBy(ctx context.Context, fn GroupByFunction) (EntityListMap, error) {
 	resultSet := EntityListMap{}
 	for _, entity := range c.entities {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		keys := fn(&entity)
 		for _, key := range keys {
 			resultSet[key] = append(resultSet[key], entity)
@@ -47,8 +56,11 @@ func (c CacheEntitySource) GroupBy(_ context.Context, fn GroupByFunction) (Entit
 	return resultSet, nil
 }
 
-func (c CacheEntitySource) Iterate(_ context.Context, fn IteratorFunction) error {
+func (c CacheEntitySource) Iterate(ctx context.Context, fn IteratorFunction) error {
 	for _, entity := range c.entities {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if err := fn(&entity); err != nil {
 			return err
 		}
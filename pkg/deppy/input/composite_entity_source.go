@@ -0,0 +1,236 @@
+package input
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+const (
+	// PropertyOrigin is the property holding the name of the source an
+	// entity of a CompositeEntitySource comes from.
+	PropertyOrigin = "deppy.origin"
+	// PropertyPriority is the property holding the priority of the
+	// source an entity of a CompositeEntitySource comes from.
+	PropertyPriority = "deppy.priority"
+)
+
+// CollisionPolicy determines how a CompositeEntitySource handles
+// entities with the same Identifier in different sources.
+type CollisionPolicy int
+
+const (
+	// Deduplicate keeps only the entity of the source with the
+	// highest priority. Sources with the same priority take
+	// precedence in the order they are given.
+	Deduplicate CollisionPolicy = iota
+	// Namespace prefixes the Identifier of every entity with the name
+	// of its source and a slash, so that all of them are kept.
+	Namespace
+)
+
+// NamedEntitySource is an EntitySource that is part of a
+// CompositeEntitySource.
+type NamedEntitySource struct {
+	// Name identifies the source. It must be unique within a
+	// CompositeEntitySource and, with the Namespace policy, must not
+	// contain a slash.
+	Name string
+	// Priority orders the sources. Entities of sources with a higher
+	// priority are preferred.
+	Priority int
+	Source   EntitySource
+}
+
+var (
+	_ EntitySource = &CompositeEntitySource{}
+	_ EntityGetter = &CompositeEntitySource{}
+)
+
+// CompositeEntitySource federates multiple EntitySources. Every entity
+// it returns is a copy of the entity of the underlying source with the
+// PropertyOrigin and PropertyPriority properties set. Results are
+// ordered by decreasing source priority.
+type CompositeEntitySource struct {
+	policy  CollisionPolicy
+	sources []NamedEntitySource
+}
+
+// NewCompositeEntitySource returns a CompositeEntitySource over the
+// given sources, handling colliding Identifiers according to policy.
+func NewCompositeEntitySource(policy CollisionPolicy, sources ...NamedEntitySource) (*CompositeEntitySource, error) {
+	names := make(map[string]struct{}, len(sources))
+	for _, source := range sources {
+		if _, ok := names[source.Name]; ok {
+			return nil, fmt.Errorf("duplicate entity source name %q", source.Name)
+		}
+		if policy == Namespace && strings.Contains(source.Name, "/") {
+			return nil, fmt.Errorf("entity source name %q contains a slash", source.Name)
+		}
+		names[source.Name] = struct{}{}
+	}
+	ordered := make([]NamedEntitySource, len(sources))
+	copy(ordered, sources)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return ordered[i].Priority > ordered[j].Priority
+	})
+	return &CompositeEntitySource{
+		policy:  policy,
+		sources: ordered,
+	}, nil
+}
+
+func (c *CompositeEntitySource) Get(ctx context.Context, id deppy.Identifier) *Entity {
+	entity, err := c.GetEntity(ctx, id)
+	if err != nil {
+		return nil
+	}
+	return entity
+}
+
+// GetEntity returns the entity with the given Identifier, a
+// NotFoundError if no source has one, or the first error returned by a
+// source, annotated with its name.
+func (c *CompositeEntitySource) GetEntity(ctx context.Context, id deppy.Identifier) (*Entity, error) {
+	sources := c.sources
+	local := id
+	if c.policy == Namespace {
+		name, rest, ok := strings.Cut(string(id), "/")
+		if !ok {
+			return nil, NotFoundError(id)
+		}
+		sources = nil
+		for _, source := range c.sources {
+			if source.Name == name {
+				sources = []NamedEntitySource{source}
+				local = deppy.Identifier(rest)
+				break
+			}
+		}
+	}
+	for _, source := range sources {
+		entity, err := AsEntitySourceV2(source.Source).Get(ctx, local)
+		if IsNotFound(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error getting entity %q from source %q: %w", local, source.Name, err)
+		}
+		return c.annotate(source, entity), nil
+	}
+	return nil, NotFoundError(id)
+}
+
+func (c *CompositeEntitySource) Filter(ctx context.Context, filter Predicate) (EntityList, error) {
+	resultSet := EntityList{}
+	err := c.Iterate(ctx, func(entity *Entity) error {
+		if filter(entity) {
+			resultSet = append(resultSet, *entity)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resultSet, nil
+}
+
+func (c *CompositeEntitySource) GroupBy(ctx context.Context, fn GroupByFunction) (EntityListMap, error) {
+	resultSet := EntityListMap{}
+	err := c.Iterate(ctx, func(entity *Entity) error {
+		for _, key := range fn(entity) {
+			resultSet[key] = append(resultSet[key], *entity)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resultSet, nil
+}
+
+// Iterate calls fn with the entities of every source, by decreasing
+// source priority. Errors returned by fn are returned as they are,
+// while errors of the underlying sources are annotated with the name
+// of the source.
+func (c *CompositeEntitySource) Iterate(ctx context.Context, fn IteratorFunction) error {
+	var seen map[deppy.Identifier]struct{}
+	if c.policy == Deduplicate && len(c.sources) > 1 {
+		seen = map[deppy.Identifier]struct{}{}
+	}
+	var fnErr error
+	for _, source := range c.sources {
+		err := source.Source.Iterate(ctx, func(entity *Entity) error {
+			if seen != nil {
+				if _, ok := seen[entity.ID]; ok {
+					return nil
+				}
+				seen[entity.ID] = struct{}{}
+			}
+			fnErr = fn(c.annotate(source, entity))
+			return fnErr
+		})
+		if fnErr != nil {
+			return fnErr
+		}
+		if err != nil {
+			return fmt.Errorf("error iterating entities of source %q: %w", source.Name, err)
+		}
+	}
+	return nil
+}
+
+// annotate returns a copy of entity with the origin and priority of
+// source, and namespaced if required by the collision policy.
+func (c *CompositeEntitySource) annotate(source NamedEntitySource, entity *Entity) *Entity {
+	annotated := &Entity{
+		ID:         entity.ID,
+		Properties: make(map[string]string, len(entity.Properties)+2),
+	}
+	if c.policy == Namespace {
+		annotated.ID = deppy.Identifier(source.Name + "/" + string(entity.ID))
+	}
+	for k, v := range entity.Properties {
+		annotated.Properties[k] = v
+	}
+	if entity.Values != nil {
+		annotated.Values = make(map[string]Value, len(entity.Values)+2)
+		for k, v := range entity.Values {
+			annotated.Values[k] = v
+		}
+	}
+	annotated.SetValue(PropertyOrigin, StringValue(source.Name))
+	annotated.SetValue(PropertyPriority, IntValue(int64(source.Priority)))
+	return annotated
+}
+
+// OriginOf returns the name of the source of an entity returned by a
+// CompositeEntitySource.
+func OriginOf(entity *Entity) (string, bool) {
+	return entity.StringProperty(PropertyOrigin)
+}
+
+// PriorityOf returns the priority of the source of an entity returned
+// by a CompositeEntitySource, or zero if it has none.
+func PriorityOf(entity *Entity) int {
+	priority, _ := entity.IntProperty(PropertyPriority)
+	return int(priority)
+}
+
+// ByPriority returns a SortFunction ordering entities by decreasing
+// source priority, and by less within the same priority. It lets
+// variable sources list candidates of higher priority catalogs first.
+func ByPriority(less SortFunction) SortFunction {
+	return func(e1 *Entity, e2 *Entity) bool {
+		if p1, p2 := PriorityOf(e1), PriorityOf(e2); p1 != p2 {
+			return p1 > p2
+		}
+		if less == nil {
+			return false
+		}
+		return less(e1, e2)
+	}
+}
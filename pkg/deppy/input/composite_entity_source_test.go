@@ -0,0 +1,155 @@
+package input_test
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+type failingEntitySource struct {
+	input.EntitySource
+}
+
+func (failingEntitySource) Iterate(context.Context, input.IteratorFunction) error {
+	return errors.New("unavailable")
+}
+
+var _ = Describe("CompositeEntitySource", func() {
+	var (
+		ctx              context.Context
+		community, certs input.NamedEntitySource
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		community = input.NamedEntitySource{
+			Name:     "community",
+			Priority: 0,
+			Source: input.NewCacheQuerier(map[deppy.Identifier]input.Entity{
+				"etcd.v1.0.0":       *input.NewEntity("etcd.v1.0.0", map[string]string{"package": "etcd"}),
+				"prometheus.v1.0.0": *input.NewEntity("prometheus.v1.0.0", map[string]string{"package": "prometheus"}),
+			}),
+		}
+		certs = input.NamedEntitySource{
+			Name:     "certified",
+			Priority: 10,
+			Source: input.NewCacheQuerier(map[deppy.Identifier]input.Entity{
+				"etcd.v1.0.0": *input.NewEntity("etcd.v1.0.0", map[string]string{"package": "etcd", "certified": "true"}),
+			}),
+		}
+	})
+
+	It("should reject duplicate source names", func() {
+		_, err := input.NewCompositeEntitySource(input.Deduplicate, community, community)
+		Expect(err).To(MatchError(`duplicate entity source name "community"`))
+	})
+
+	It("should reject source names with slashes when namespacing", func() {
+		community.Name = "a/b"
+		_, err := input.NewCompositeEntitySource(input.Namespace, community)
+		Expect(err).To(MatchError(`entity source name "a/b" contains a slash`))
+	})
+
+	When("deduplicating", func() {
+		var source *input.CompositeEntitySource
+
+		BeforeEach(func() {
+			var err error
+			source, err = input.NewCompositeEntitySource(input.Deduplicate, community, certs)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should prefer the entity of the source with the highest priority", func() {
+			entity := source.Get(ctx, "etcd.v1.0.0")
+			Expect(entity).NotTo(BeNil())
+			Expect(entity.Properties).To(HaveKeyWithValue("certified", "true"))
+			origin, ok := input.OriginOf(entity)
+			Expect(ok).To(BeTrue())
+			Expect(origin).To(Equal("certified"))
+			Expect(input.PriorityOf(entity)).To(Equal(10))
+			Expect(source.Get(ctx, "missing")).To(BeNil())
+		})
+
+		It("should return each identifier once", func() {
+			el, err := source.Filter(ctx, func(*input.Entity) bool { return true })
+			Expect(err).NotTo(HaveOccurred())
+			Expect(el.CollectIds()).To(Equal([]deppy.Identifier{"etcd.v1.0.0", "prometheus.v1.0.0"}))
+			Expect(el[0].Properties).To(HaveKeyWithValue(input.PropertyOrigin, "certified"))
+			Expect(el[1].Properties).To(HaveKeyWithValue(input.PropertyOrigin, "community"))
+		})
+
+		It("should not modify the entities of the underlying sources", func() {
+			_ = source.Get(ctx, "prometheus.v1.0.0")
+			Expect(community.Source.Get(ctx, "prometheus.v1.0.0").Properties).NotTo(HaveKey(input.PropertyOrigin))
+		})
+
+		It("should group entities", func() {
+			grouped, err := source.GroupBy(ctx, func(entity *input.Entity) []string {
+				origin, _ := input.OriginOf(entity)
+				return []string{origin}
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(grouped["certified"].CollectIds()).To(Equal([]deppy.Identifier{"etcd.v1.0.0"}))
+			Expect(grouped["community"].CollectIds()).To(Equal([]deppy.Identifier{"prometheus.v1.0.0"}))
+		})
+	})
+
+	When("namespacing", func() {
+		var source *input.CompositeEntitySource
+
+		BeforeEach(func() {
+			var err error
+			source, err = input.NewCompositeEntitySource(input.Namespace, community, certs)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should keep every entity under the name of its source", func() {
+			var ids []deppy.Identifier
+			Expect(source.Iterate(ctx, func(entity *input.Entity) error {
+				ids = append(ids, entity.ID)
+				return nil
+			})).To(Succeed())
+			Expect(ids).To(ConsistOf(deppy.Identifier("certified/etcd.v1.0.0"), deppy.Identifier("community/etcd.v1.0.0"), deppy.Identifier("community/prometheus.v1.0.0")))
+			Expect(ids[0]).To(Equal(deppy.Identifier("certified/etcd.v1.0.0")))
+		})
+
+		It("should get entities by namespaced identifier", func() {
+			entity := source.Get(ctx, "community/etcd.v1.0.0")
+			Expect(entity).NotTo(BeNil())
+			Expect(entity.ID).To(Equal(deppy.Identifier("community/etcd.v1.0.0")))
+			Expect(entity.Properties).NotTo(HaveKey("certified"))
+			Expect(source.Get(ctx, "etcd.v1.0.0")).To(BeNil())
+			Expect(source.Get(ctx, "other/etcd.v1.0.0")).To(BeNil())
+		})
+	})
+
+	It("should annotate errors of the underlying sources with their name", func() {
+		source, err := input.NewCompositeEntitySource(input.Deduplicate, community, input.NamedEntitySource{Name: "broken", Source: failingEntitySource{}})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = source.Filter(ctx, func(*input.Entity) bool { return true })
+		Expect(err).To(MatchError(`error iterating entities of source "broken": unavailable`))
+	})
+
+	It("should return errors of the iterator function as they are", func() {
+		source, err := input.NewCompositeEntitySource(input.Deduplicate, community)
+		Expect(err).NotTo(HaveOccurred())
+		stop := errors.New("stop")
+		Expect(source.Iterate(ctx, func(*input.Entity) error { return stop })).To(BeIdenticalTo(stop))
+	})
+
+	It("should sort entities by decreasing priority", func() {
+		el := input.EntityList{
+			*input.NewEntity("b", map[string]string{input.PropertyPriority: "0"}),
+			*input.NewEntity("a", map[string]string{input.PropertyPriority: "0"}),
+			*input.NewEntity("c", map[string]string{input.PropertyPriority: "10"}),
+			*input.NewEntity("d", nil),
+		}
+		el.Sort(input.ByPriority(func(e1, e2 *input.Entity) bool { return e1.ID < e2.ID }))
+		Expect(el.CollectIds()).To(Equal([]deppy.Identifier{"c", "a", "b", "d"}))
+	})
+})
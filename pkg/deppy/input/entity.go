@@ -1,21 +1,180 @@
 package input
 
 import (
+	"encoding/json"
+
+	"github.com/blang/semver/v4"
+
 	"github.com/operator-framework/deppy/pkg/deppy"
 )
 
+// Entity is a named set of properties. Properties holds the string
+// form of every property, while Values holds the typed values of the
+// properties set with SetValue. For the properties it holds, Values is
+// authoritative: every accessor reads it first, and SetValue and
+// decoding from JSON keep Properties in sync with it. The typed
+// accessors fall back to parsing Properties, so entities built from
+// string maps alone can be read with them too.
 type Entity struct {
 	ID         deppy.Identifier  `json:"identifier"`
 	Properties map[string]string `json:"properties"`
+	Values     map[string]Value  `json:"values,omitempty"`
 }
 
 func (e *Entity) Identifier() deppy.Identifier {
 	return e.ID
 }
 
+// copyEntity returns a copy of entity that doesn't share its property
+// maps. Values themselves are immutable and are shared.
+func copyEntity(entity Entity) Entity {
+	copied := Entity{ID: entity.ID}
+	if entity.Properties != nil {
+		copied.Properties = make(map[string]string, len(entity.Properties))
+		for name, property := range entity.Properties {
+			copied.Properties[name] = property
+		}
+	}
+	if entity.Values != nil {
+		copied.Values = make(map[string]Value, len(entity.Values))
+		for name, value := range entity.Values {
+			copied.Values[name] = value
+		}
+	}
+	return copied
+}
+
+// NewEntity returns an Entity with a copy of the given properties.
 func NewEntity(id deppy.Identifier, properties map[string]string) *Entity {
-	return &Entity{
-		ID:         id,
-		Properties: properties,
+	entity := copyEntity(Entity{ID: id, Properties: properties})
+	return &entity
+}
+
+// NewEntityWithValues returns an Entity with the given typed property
+// values.
+func NewEntityWithValues(id deppy.Identifier, values map[string]Value) *Entity {
+	entity := &Entity{ID: id}
+	for name, value := range values {
+		entity.SetValue(name, value)
+	}
+	return entity
+}
+
+// SetValue sets the typed value of the property with the given name,
+// and its string form in Properties.
+func (e *Entity) SetValue(name string, value Value) {
+	if e.Values == nil {
+		e.Values = map[string]Value{}
+	}
+	if e.Properties == nil {
+		e.Properties = map[string]string{}
+	}
+	e.Values[name] = value
+	e.Properties[name] = value.String()
+}
+
+// Value returns the typed value of the property with the given name.
+// Properties only present in Properties are returned as strings.
+func (e *Entity) Value(name string) (Value, bool) {
+	if value, ok := e.Values[name]; ok {
+		return value, true
+	}
+	if s, ok := e.Properties[name]; ok {
+		return StringValue(s), true
+	}
+	return Value{}, false
+}
+
+// StringProperty returns the string form of the property with the
+// given name.
+func (e *Entity) StringProperty(name string) (string, bool) {
+	if value, ok := e.Values[name]; ok {
+		return value.String(), true
+	}
+	s, ok := e.Properties[name]
+	return s, ok
+}
+
+// IntProperty returns the property with the given name as an integer.
+func (e *Entity) IntProperty(name string) (int64, bool) {
+	value, ok := e.typed(name, IntKind)
+	if !ok {
+		return 0, false
+	}
+	return value.AsInt()
+}
+
+// BoolProperty returns the property with the given name as a boolean.
+func (e *Entity) BoolProperty(name string) (bool, bool) {
+	value, ok := e.typed(name, BoolKind)
+	if !ok {
+		return false, false
+	}
+	return value.AsBool()
+}
+
+// SemverProperty returns the property with the given name as a
+// semantic version.
+func (e *Entity) SemverProperty(name string) (semver.Version, bool) {
+	value, ok := e.typed(name, SemverKind)
+	if !ok {
+		return semver.Version{}, false
+	}
+	return value.AsSemver()
+}
+
+// ListProperty returns the property with the given name as a list.
+// String properties are parsed as JSON arrays.
+func (e *Entity) ListProperty(name string) ([]Value, bool) {
+	value, ok := e.typed(name, ListKind)
+	if !ok {
+		return nil, false
+	}
+	return value.AsList()
+}
+
+// ObjectProperty returns the property with the given name as an
+// object. String properties are parsed as JSON objects.
+func (e *Entity) ObjectProperty(name string) (map[string]Value, bool) {
+	value, ok := e.typed(name, ObjectKind)
+	if !ok {
+		return nil, false
+	}
+	return value.AsObject()
+}
+
+// typed returns the value of the property with the given name if it
+// is of the given kind, or if it is a string that parses as one.
+func (e *Entity) typed(name string, kind ValueKind) (Value, bool) {
+	value, ok := e.Value(name)
+	if !ok {
+		return Value{}, false
+	}
+	if value.Kind() == kind {
+		return value, true
+	}
+	s, ok := value.AsString()
+	if !ok {
+		return Value{}, false
+	}
+	value, err := ParseValue(kind, s)
+	return value, err == nil
+}
+
+// UnmarshalJSON decodes an Entity, setting the string form of its typed
+// values in its properties.
+func (e *Entity) UnmarshalJSON(data []byte) error {
+	type entity Entity
+	var decoded entity
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return err
+	}
+	*e = Entity(decoded)
+	for name, value := range e.Values {
+		if e.Properties == nil {
+			e.Properties = map[string]string{}
+		}
+		e.Properties[name] = value.String()
 	}
+	return nil
 }
@@ -0,0 +1,120 @@
+package input
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+// EntitySourceHandler serves an EntitySource over the HTTP protocol
+// understood by HTTPEntitySource, relative to the path it is mounted
+// at. It is a reference implementation: every page is computed by
+// iterating over the whole source.
+type EntitySourceHandler struct {
+	source EntitySource
+}
+
+var _ http.Handler = &EntitySourceHandler{}
+
+// NewEntitySourceHandler returns an EntitySourceHandler serving source.
+// Mount it with http.StripPrefix to serve it below the root path.
+func NewEntitySourceHandler(source EntitySource) *EntitySourceHandler {
+	return &EntitySourceHandler{source: source}
+}
+
+func (h *EntitySourceHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", r.Method))
+		return
+	}
+	path := strings.TrimPrefix(r.URL.EscapedPath(), "/")
+	switch {
+	case path == entitiesPath:
+		h.serveEntities(w, r)
+	case strings.HasPrefix(path, entitiesPath+"/"):
+		id, err := url.PathUnescape(strings.TrimPrefix(path, entitiesPath+"/"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid identifier: %w", err))
+			return
+		}
+		h.serveEntity(w, r, deppy.Identifier(id))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("no such endpoint %q", r.URL.Path))
+	}
+}
+
+func (h *EntitySourceHandler) serveEntity(w http.ResponseWriter, r *http.Request, id deppy.Identifier) {
+	entity := h.source.Get(r.Context(), id)
+	if entity == nil {
+		writeError(w, http.StatusNotFound, fmt.Errorf("entity %q not found", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, entity)
+}
+
+func (h *EntitySourceHandler) serveEntities(w http.ResponseWriter, r *http.Request) {
+	params := r.URL.Query()
+	limit := defaultPageSize
+	if s := params.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n <= 0 || n > maxPageSize {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("limit must be an integer between 1 and %d", maxPageSize))
+			return
+		}
+		limit = n
+	}
+	after := deppy.Identifier(params.Get("continue"))
+	filter := func(*Entity) bool { return true }
+	if query := params.Get("query"); query != "" {
+		predicate, err := CompileQuery(query)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		filter = predicate
+	}
+
+	page := entityPage{Entities: []Entity{}}
+	err := h.source.Iterate(r.Context(), func(entity *Entity) error {
+		if entity.ID > after && filter(entity) {
+			page.Entities = append(page.Entities, *entity)
+		}
+		return nil
+	})
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, r.Context().Err()) {
+			// the client is gone, but the status is still logged
+			status = http.StatusServiceUnavailable
+		}
+		writeError(w, status, err)
+		return
+	}
+	sort.Slice(page.Entities, func(i, j int) bool {
+		return page.Entities[i].ID < page.Entities[j].ID
+	})
+	if len(page.Entities) > limit {
+		page.Entities = page.Entities[:limit]
+		page.Continue = string(page.Entities[limit-1].ID)
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	// the status has been sent, so there is no way to report errors
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorBody{Error: err.Error()})
+}
@@ -99,6 +99,9 @@ var _ = Describe("EntitySource", func() {
 		Describe("Filter", func() {
 			It("should return entities that meet filter predicates", func() {
 				id := func(element interface{}) string {
+					if entity, ok := element.(input.Entity); ok {
+						return fmt.Sprintf("{%s %v}", entity.ID, entity.Properties)
+					}
 					return fmt.Sprintf("%v", element)
 				}
 				el, err := entitySource.Filter(context.Background(), input.Or(byIndex("2"), bySource("1")))
@@ -155,6 +158,9 @@ var _ = Describe("EntitySource", func() {
 		Describe("GroupBy", func() {
 			It("should group entities by the keys provided by the groupBy function", func() {
 				id := func(element interface{}) string {
+					if entity, ok := element.(input.Entity); ok {
+						return fmt.Sprintf("{%s %v}", entity.ID, entity.Properties)
+					}
 					return fmt.Sprintf("%v", element)
 				}
 				grouped, err := entitySource.GroupBy(context.Background(), bySourceAndIndex)
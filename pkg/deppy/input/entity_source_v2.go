@@ -0,0 +1,161 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+// NotFoundError is returned by EntitySourceV2.Get when there is no
+// entity with the requested Identifier.
+type NotFoundError deppy.Identifier
+
+func (e NotFoundError) Error() string {
+	return fmt.Sprintf("entity %q not found", deppy.Identifier(e))
+}
+
+// IsNotFound returns true if err is, or wraps, a NotFoundError.
+func IsNotFound(err error) bool {
+	var notFound NotFoundError
+	return errors.As(err, &notFound)
+}
+
+// EntitySourceV2 is like EntitySource, except that Get reports why it
+// couldn't return an entity, with a NotFoundError if there is none,
+// and that every method returns the error of its context once it is
+// done. Use AsEntitySourceV2 to adapt an EntitySource, and
+// WrapEntitySourceV2 to use an EntitySourceV2 where an EntitySource is
+// expected.
+type EntitySourceV2 interface {
+	Get(ctx context.Context, id deppy.Identifier) (*Entity, error)
+	Filter(ctx context.Context, filter Predicate) (EntityList, error)
+	GroupBy(ctx context.Context, fn GroupByFunction) (EntityListMap, error)
+	Iterate(ctx context.Context, fn IteratorFunction) error
+}
+
+// EntityGetter is implemented by EntitySources that can report why Get
+// returns nil. GetEntity returns the same entity as Get, or the reason
+// there is none: a NotFoundError if the entity doesn't exist, or any
+// other error if it couldn't be fetched.
+type EntityGetter interface {
+	GetEntity(ctx context.Context, id deppy.Identifier) (*Entity, error)
+}
+
+// AdaptedEntitySourceV1 is an EntitySourceV2 implemented by an
+// EntitySource.
+type AdaptedEntitySourceV1 struct {
+	EntitySource EntitySource
+}
+
+var _ EntitySourceV2 = AdaptedEntitySourceV1{}
+
+// Get returns the entity with the given Identifier. It uses GetEntity
+// if the adapted source is an EntityGetter, and otherwise reports a
+// NotFoundError whenever the adapted source returns nil.
+func (a AdaptedEntitySourceV1) Get(ctx context.Context, id deppy.Identifier) (*Entity, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if getter, ok := a.EntitySource.(EntityGetter); ok {
+		return getter.GetEntity(ctx, id)
+	}
+	if entity := a.EntitySource.Get(ctx, id); entity != nil {
+		return entity, nil
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, NotFoundError(id)
+}
+
+// Filter filters the entities of the adapted source, without
+// evaluating filter any further once ctx is done.
+func (a AdaptedEntitySourceV1) Filter(ctx context.Context, filter Predicate) (EntityList, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	resultSet, err := a.EntitySource.Filter(ctx, func(entity *Entity) bool {
+		return ctx.Err() == nil && filter(entity)
+	})
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return resultSet, err
+}
+
+// GroupBy groups the entities of the adapted source, without calling
+// fn any further once ctx is done.
+func (a AdaptedEntitySourceV1) GroupBy(ctx context.Context, fn GroupByFunction) (EntityListMap, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	resultSet, err := a.EntitySource.GroupBy(ctx, func(entity *Entity) []string {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return fn(entity)
+	})
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return nil, ctxErr
+	}
+	return resultSet, err
+}
+
+// Iterate iterates over the entities of the adapted source, stopping
+// once ctx is done.
+func (a AdaptedEntitySourceV1) Iterate(ctx context.Context, fn IteratorFunction) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return a.EntitySource.Iterate(ctx, func(entity *Entity) error {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return fn(entity)
+	})
+}
+
+// WrappedEntitySourceV2 is an EntitySource implemented by an
+// EntitySourceV2.
+type WrappedEntitySourceV2 struct {
+	EntitySourceV2
+}
+
+var (
+	_ EntitySource = &WrappedEntitySourceV2{}
+	_ EntityGetter = &WrappedEntitySourceV2{}
+)
+
+// WrapEntitySourceV2 returns an EntitySource implemented by the given
+// EntitySourceV2.
+func WrapEntitySourceV2(source EntitySourceV2) *WrappedEntitySourceV2 {
+	return &WrappedEntitySourceV2{EntitySourceV2: source}
+}
+
+// Get returns the entity with the given Identifier, or nil if the
+// wrapped source returns an error. Use GetEntity to get the error.
+func (w *WrappedEntitySourceV2) Get(ctx context.Context, id deppy.Identifier) *Entity {
+	entity, err := w.EntitySourceV2.Get(ctx, id)
+	if err != nil {
+		return nil
+	}
+	return entity
+}
+
+// GetEntity returns the entity with the given Identifier, or the error
+// returned by the wrapped source.
+func (w *WrappedEntitySourceV2) GetEntity(ctx context.Context, id deppy.Identifier) (*Entity, error) {
+	return w.EntitySourceV2.Get(ctx, id)
+}
+
+// AsEntitySourceV2 returns the EntitySourceV2 implementing source if it
+// was created by WrapEntitySourceV2, and source adapted to
+// EntitySourceV2 otherwise.
+func AsEntitySourceV2(source EntitySource) EntitySourceV2 {
+	if w, ok := source.(*WrappedEntitySourceV2); ok {
+		return w.EntitySourceV2
+	}
+	return AdaptedEntitySourceV1{EntitySource: source}
+}
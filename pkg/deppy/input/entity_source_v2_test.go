@@ -0,0 +1,173 @@
+package input_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+var _ = Describe("EntitySourceV2", func() {
+	var (
+		ctx      context.Context
+		entities map[deppy.Identifier]input.Entity
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		entities = map[deppy.Identifier]input.Entity{}
+		for i := 0; i < 10; i++ {
+			id := deppy.Identifier(fmt.Sprint(i))
+			entities[id] = *input.NewEntity(id, nil)
+		}
+	})
+
+	It("should report missing entities with a NotFoundError", func() {
+		source := input.AsEntitySourceV2(input.NewCacheQuerier(entities))
+		entity, err := source.Get(ctx, "1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entity.ID).To(Equal(deppy.Identifier("1")))
+
+		_, err = source.Get(ctx, "missing")
+		Expect(err).To(MatchError(`entity "missing" not found`))
+		Expect(input.IsNotFound(err)).To(BeTrue())
+		Expect(input.IsNotFound(fmt.Errorf("wrapped: %w", err))).To(BeTrue())
+		Expect(input.IsNotFound(errors.New("other"))).To(BeFalse())
+	})
+
+	It("should tell remote failures apart from missing entities", func() {
+		var fail bool
+		handler := input.NewEntitySourceHandler(input.NewCacheQuerier(entities))
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if fail {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			handler.ServeHTTP(w, r)
+		}))
+		defer server.Close()
+		remote, err := input.NewHTTPEntitySource(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		source := input.AsEntitySourceV2(remote)
+
+		_, err = source.Get(ctx, "missing")
+		Expect(input.IsNotFound(err)).To(BeTrue())
+
+		fail = true
+		_, err = source.Get(ctx, "1")
+		Expect(err).To(HaveOccurred())
+		Expect(input.IsNotFound(err)).To(BeFalse())
+		var remoteErr *input.RemoteError
+		Expect(errors.As(err, &remoteErr)).To(BeTrue())
+		Expect(remoteErr.StatusCode).To(Equal(http.StatusServiceUnavailable))
+	})
+
+	It("should report errors of the sources of a composite", func() {
+		composite, err := input.NewCompositeEntitySource(input.Deduplicate,
+			input.NamedEntitySource{Name: "local", Priority: 1, Source: input.NewCacheQuerier(entities)},
+			input.NamedEntitySource{Name: "broken", Source: input.WrapEntitySourceV2(failingEntitySourceV2{})},
+		)
+		Expect(err).NotTo(HaveOccurred())
+		source := input.AsEntitySourceV2(composite)
+
+		entity, err := source.Get(ctx, "1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entity).NotTo(BeNil())
+
+		_, err = source.Get(ctx, "missing")
+		Expect(err).To(MatchError(`error getting entity "missing" from source "broken": unavailable`))
+	})
+
+	It("should stop iterating once the context is done", func() {
+		cancelled, cancel := context.WithCancel(ctx)
+		source := input.AsEntitySourceV2(input.NewCacheQuerier(entities))
+
+		var calls int
+		err := source.Iterate(cancelled, func(*input.Entity) error {
+			calls++
+			cancel()
+			return nil
+		})
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(calls).To(Equal(1))
+
+		_, err = source.Filter(cancelled, func(*input.Entity) bool { return true })
+		Expect(err).To(MatchError(context.Canceled))
+		_, err = source.GroupBy(cancelled, func(*input.Entity) []string { return nil })
+		Expect(err).To(MatchError(context.Canceled))
+		_, err = source.Get(cancelled, "1")
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("should stop evaluating predicates once the context is done", func() {
+		cancelled, cancel := context.WithCancel(ctx)
+		// the adapted source doesn't check the context itself
+		source := input.AsEntitySourceV2(ignoringContext{input.NewCacheQuerier(entities)})
+
+		var calls int
+		_, err := source.Filter(cancelled, func(*input.Entity) bool {
+			calls++
+			cancel()
+			return true
+		})
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(calls).To(Equal(1))
+
+		calls = 0
+		cancelled, cancel = context.WithCancel(ctx)
+		_, err = source.GroupBy(cancelled, func(*input.Entity) []string {
+			calls++
+			cancel()
+			return nil
+		})
+		Expect(err).To(MatchError(context.Canceled))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("should unwrap wrapped sources", func() {
+		v2 := failingEntitySourceV2{}
+		wrapped := input.WrapEntitySourceV2(v2)
+		Expect(input.AsEntitySourceV2(wrapped)).To(Equal(v2))
+		Expect(wrapped.Get(ctx, "1")).To(BeNil())
+		_, err := wrapped.GetEntity(ctx, "1")
+		Expect(err).To(MatchError("unavailable"))
+	})
+})
+
+type failingEntitySourceV2 struct{}
+
+func (failingEntitySourceV2) Get(context.Context, deppy.Identifier) (*input.Entity, error) {
+	return nil, errors.New("unavailable")
+}
+
+func (failingEntitySourceV2) Filter(context.Context, input.Predicate) (input.EntityList, error) {
+	return nil, errors.New("unavailable")
+}
+
+func (failingEntitySourceV2) GroupBy(context.Context, input.GroupByFunction) (input.EntityListMap, error) {
+	return nil, errors.New("unavailable")
+}
+
+func (failingEntitySourceV2) Iterate(context.Context, input.IteratorFunction) error {
+	return errors.New("unavailable")
+}
+
+// ignoringContext passes a background context to the wrapped source.
+type ignoringContext struct {
+	input.EntitySource
+}
+
+func (i ignoringContext) Filter(_ context.Context, filter input.Predicate) (input.EntityList, error) {
+	return i.EntitySource.Filter(context.Background(), filter)
+}
+
+func (i ignoringContext) GroupBy(_ context.Context, fn input.GroupByFunction) (input.EntityListMap, error) {
+	return i.EntitySource.GroupBy(context.Background(), fn)
+}
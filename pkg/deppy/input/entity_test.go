@@ -1,6 +1,9 @@
 package input_test
 
 import (
+	"encoding/json"
+
+	"github.com/blang/semver/v4"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
@@ -25,4 +28,136 @@ var _ = Describe("Entity", func() {
 		Expect(value).To(Equal(""))
 		Expect(ok).To(BeFalse())
 	})
+
+	It("stores typed values along with their string form", func() {
+		entity := input.NewEntityWithValues("id", map[string]input.Value{
+			"name":    input.StringValue("etcd"),
+			"count":   input.IntValue(3),
+			"enabled": input.BoolValue(true),
+			"version": input.SemverValue(semver.MustParse("1.2.3")),
+			"tags":    input.ListValue(input.StringValue("a"), input.StringValue("b")),
+			"gvk":     input.ObjectValue(map[string]input.Value{"kind": input.StringValue("Foo")}),
+		})
+		Expect(entity.Properties).To(Equal(map[string]string{
+			"name":    "etcd",
+			"count":   "3",
+			"enabled": "true",
+			"version": "1.2.3",
+			"tags":    `["a","b"]`,
+			"gvk":     `{"kind":"Foo"}`,
+		}))
+
+		count, ok := entity.IntProperty("count")
+		Expect(ok).To(BeTrue())
+		Expect(count).To(Equal(int64(3)))
+		enabled, ok := entity.BoolProperty("enabled")
+		Expect(ok).To(BeTrue())
+		Expect(enabled).To(BeTrue())
+		version, ok := entity.SemverProperty("version")
+		Expect(ok).To(BeTrue())
+		Expect(version).To(Equal(semver.MustParse("1.2.3")))
+		tags, ok := entity.ListProperty("tags")
+		Expect(ok).To(BeTrue())
+		Expect(tags).To(Equal([]input.Value{input.StringValue("a"), input.StringValue("b")}))
+		gvk, ok := entity.ObjectProperty("gvk")
+		Expect(ok).To(BeTrue())
+		Expect(gvk).To(HaveKeyWithValue("kind", input.StringValue("Foo")))
+
+		_, ok = entity.IntProperty("name")
+		Expect(ok).To(BeFalse())
+		_, ok = entity.BoolProperty("missing")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("parses string properties with the typed accessors", func() {
+		entity := input.NewEntity("id", map[string]string{
+			"count":   "42",
+			"version": "0.9.1",
+			"gvks":    `[{"group":"g","version":"v1","kind":"K"}]`,
+			"bad":     "not a number",
+		})
+		count, ok := entity.IntProperty("count")
+		Expect(ok).To(BeTrue())
+		Expect(count).To(Equal(int64(42)))
+		version, ok := entity.SemverProperty("version")
+		Expect(ok).To(BeTrue())
+		Expect(version).To(Equal(semver.MustParse("0.9.1")))
+		gvks, ok := entity.ListProperty("gvks")
+		Expect(ok).To(BeTrue())
+		Expect(gvks).To(HaveLen(1))
+		kind, ok := gvks[0].Get("kind")
+		Expect(ok).To(BeTrue())
+		Expect(kind).To(Equal(input.StringValue("K")))
+		_, ok = entity.IntProperty("bad")
+		Expect(ok).To(BeFalse())
+		value, ok := entity.Value("bad")
+		Expect(ok).To(BeTrue())
+		Expect(value).To(Equal(input.StringValue("not a number")))
+	})
+
+	It("round-trips typed values through JSON", func() {
+		entity := input.NewEntityWithValues("id", map[string]input.Value{
+			"name":  input.StringValue("etcd"),
+			"count": input.IntValue(-7),
+			"flag":  input.BoolValue(false),
+			"list":  input.ListValue(input.IntValue(1), input.ListValue()),
+			"obj":   input.ObjectValue(map[string]input.Value{"nested": input.ObjectValue(map[string]input.Value{})}),
+		})
+		data, err := json.Marshal(entity)
+		Expect(err).ToNot(HaveOccurred())
+		var decoded input.Entity
+		Expect(json.Unmarshal(data, &decoded)).To(Succeed())
+		Expect(&decoded).To(Equal(entity))
+	})
+
+	It("fills in string properties of typed values decoded from JSON", func() {
+		var entity input.Entity
+		Expect(json.Unmarshal([]byte(`{"identifier":"id","values":{"count":3,"version":"1.0.0"}}`), &entity)).To(Succeed())
+		Expect(entity.Properties).To(Equal(map[string]string{"count": "3", "version": "1.0.0"}))
+		version, ok := entity.SemverProperty("version")
+		Expect(ok).To(BeTrue())
+		Expect(version).To(Equal(semver.MustParse("1.0.0")))
+	})
+
+	It("reads typed values before string properties that disagree with them", func() {
+		entity := input.Entity{
+			ID:         "id",
+			Properties: map[string]string{"count": "2"},
+			Values:     map[string]input.Value{"count": input.IntValue(3)},
+		}
+		s, _ := entity.StringProperty("count")
+		Expect(s).To(Equal("3"))
+		value, _ := entity.Value("count")
+		Expect(value).To(Equal(input.IntValue(3)))
+		count, _ := entity.IntProperty("count")
+		Expect(count).To(BeEquivalentTo(3))
+
+		var decoded input.Entity
+		Expect(json.Unmarshal([]byte(`{"identifier":"id","properties":{"count":"2"},"values":{"count":3}}`), &decoded)).To(Succeed())
+		Expect(decoded.Properties).To(Equal(map[string]string{"count": "3"}))
+	})
+
+	It("rejects values that have no typed equivalent", func() {
+		var value input.Value
+		Expect(json.Unmarshal([]byte(`null`), &value)).ToNot(Succeed())
+	})
+
+	It("decodes numbers that aren't 64-bit integers as strings", func() {
+		var entity input.Entity
+		Expect(json.Unmarshal([]byte(`{"identifier":"id","values":{"ratio":1.5,"big":18446744073709551616,"small":2}}`), &entity)).To(Succeed())
+		Expect(entity.Values).To(Equal(map[string]input.Value{
+			"ratio": input.StringValue("1.5"),
+			"big":   input.StringValue("18446744073709551616"),
+			"small": input.IntValue(2),
+		}))
+		Expect(entity.Properties).To(HaveKeyWithValue("ratio", "1.5"))
+	})
+
+	It("doesn't write into the properties it is created with", func() {
+		properties := map[string]string{"prop": "value"}
+		entity := input.NewEntity("id", properties)
+		entity.SetValue("count", input.IntValue(1))
+		entity.Properties["prop"] = "changed"
+		Expect(properties).To(Equal(map[string]string{"prop": "value"}))
+	})
 })
@@ -0,0 +1,507 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+// FileError is an error found in a file loaded by a FileEntitySource.
+type FileError struct {
+	Path string
+	// Line is the line of the file the error was found at, starting
+	// from 1, or 0 if the error concerns the whole file.
+	Line int
+	Err  error
+}
+
+func (e *FileError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %v", e.Path, e.Err)
+	}
+	return fmt.Sprintf("%s:%d: %v", e.Path, e.Line, e.Err)
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// FileErrors holds every error found while loading a directory.
+type FileErrors []*FileError
+
+func (e FileErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	s := make([]string, len(e))
+	for i, err := range e {
+		s[i] = err.Error()
+	}
+	return fmt.Sprintf("%d errors loading entities: %s", len(s), strings.Join(s, "; "))
+}
+
+var _ EntitySource = &FileEntitySource{}
+
+// FileEntitySource is an EntitySource holding the entities loaded from
+// the files of a directory. See LoadEntities for the supported
+// formats. It is safe for concurrent use, including while reloading.
+type FileEntitySource struct {
+	dir    string
+	decode func(*Entity) error
+
+	mu     sync.RWMutex
+	source *IndexedEntitySource
+	state  map[string]fileState
+}
+
+// fileState is what Poll compares to detect changes to a file.
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// FileOption configures a FileEntitySource.
+type FileOption func(s *FileEntitySource)
+
+// WithEntityDecoder sets a function applied to every entity as it is
+// loaded, typically to decode some of its properties into typed Values
+// once rather than on every query. Its errors are reported like those
+// of malformed entities.
+func WithEntityDecoder(decode func(*Entity) error) FileOption {
+	return func(s *FileEntitySource) {
+		s.decode = decode
+	}
+}
+
+// NewFileEntitySource returns a FileEntitySource holding the entities
+// of the files in dir. It fails if any of them can't be loaded.
+func NewFileEntitySource(dir string, options ...FileOption) (*FileEntitySource, error) {
+	s := &FileEntitySource{dir: dir}
+	for _, option := range options {
+		option(s)
+	}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload loads the entities of the directory again. If any of them
+// can't be loaded, the receiver keeps the entities it had.
+func (s *FileEntitySource) Reload() error {
+	state, err := scanEntityFiles(s.dir)
+	if err != nil {
+		return err
+	}
+	entities, err := loadEntities(s.dir, s.decode)
+	if err != nil {
+		return err
+	}
+	source := indexEntities(entities)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.source = source
+	s.state = state
+	return nil
+}
+
+// Poll polls the directory every interval until ctx is done, and
+// reloads it whenever a file is added, removed or modified. It calls
+// onReload, if not nil, with the result of every reload. Poll blocks,
+// so it is usually run in its own goroutine. Unlike a
+// WatchableEntitySource, it doesn't report which entities changed.
+func (s *FileEntitySource) Poll(ctx context.Context, interval time.Duration, onReload func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		state, err := scanEntityFiles(s.dir)
+		if err == nil {
+			s.mu.RLock()
+			unchanged := sameFileStates(s.state, state)
+			s.mu.RUnlock()
+			if unchanged {
+				continue
+			}
+			err = s.Reload()
+		}
+		if onReload != nil {
+			onReload(err)
+		}
+	}
+}
+
+func (s *FileEntitySource) current() *IndexedEntitySource {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.source
+}
+
+func (s *FileEntitySource) Get(ctx context.Context, id deppy.Identifier) *Entity {
+	return s.current().Get(ctx, id)
+}
+
+func (s *FileEntitySource) Filter(ctx context.Context, filter Predicate) (EntityList, error) {
+	return s.current().Filter(ctx, filter)
+}
+
+func (s *FileEntitySource) GroupBy(ctx context.Context, fn GroupByFunction) (EntityListMap, error) {
+	return s.current().GroupBy(ctx, fn)
+}
+
+func (s *FileEntitySource) Iterate(ctx context.Context, fn IteratorFunction) error {
+	return s.current().Iterate(ctx, fn)
+}
+
+// LoadEntities loads the entities of every file in dir and its
+// subdirectories with one of the following extensions, ignoring other
+// files and those whose name starts with a dot:
+//
+//   - .json: an entity, or an array of entities;
+//   - .ndjson and .jsonl: one entity per line;
+//   - .yaml and .yml: one or more documents, each an entity or a
+//     sequence of entities.
+//
+// Entities have the fields "identifier", "properties" and "values", as
+// in the JSON encoding of Entity. In YAML, the scalar values of
+// properties are read as strings. Every entity must have a non-empty
+// identifier, unique across the directory. All problems are returned
+// as FileErrors.
+func LoadEntities(dir string) (map[deppy.Identifier]Entity, error) {
+	return loadEntities(dir, nil)
+}
+
+// loadEntities is LoadEntities, applying decode, if not nil, to every
+// entity loaded.
+func loadEntities(dir string, decode func(*Entity) error) (map[deppy.Identifier]Entity, error) {
+	paths, err := entityFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	entities := map[deppy.Identifier]Entity{}
+	locations := map[deppy.Identifier]string{}
+	var errs FileErrors
+	for _, path := range paths {
+		loaded, fileErrs := loadEntityFile(path, decode)
+		errs = append(errs, fileErrs...)
+		for _, l := range loaded {
+			location := fmt.Sprintf("%s:%d", path, l.line)
+			if previous, ok := locations[l.entity.ID]; ok {
+				errs = append(errs, &FileError{Path: path, Line: l.line, Err: fmt.Errorf("duplicate identifier %q, first defined at %s", l.entity.ID, previous)})
+				continue
+			}
+			locations[l.entity.ID] = location
+			entities[l.entity.ID] = l.entity
+		}
+	}
+	if len(errs) > 0 {
+		sort.SliceStable(errs, func(i, j int) bool {
+			if errs[i].Path != errs[j].Path {
+				return errs[i].Path < errs[j].Path
+			}
+			return errs[i].Line < errs[j].Line
+		})
+		return nil, errs
+	}
+	return entities, nil
+}
+
+var entityFileExtensions = map[string]struct{}{
+	".json":   {},
+	".ndjson": {},
+	".jsonl":  {},
+	".yaml":   {},
+	".yml":    {},
+}
+
+// entityFiles returns the paths of the entity files in dir, in
+// lexical order.
+func entityFiles(dir string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path != dir && strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+		if _, ok := entityFileExtensions[strings.ToLower(filepath.Ext(path))]; ok {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return paths, nil
+}
+
+func scanEntityFiles(dir string) (map[string]fileState, error) {
+	paths, err := entityFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	state := make(map[string]fileState, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		state[path] = fileState{size: info.Size(), modTime: info.ModTime()}
+	}
+	return state, nil
+}
+
+func sameFileStates(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for path, sa := range a {
+		sb, ok := b[path]
+		if !ok || sa.size != sb.size || !sa.modTime.Equal(sb.modTime) {
+			return false
+		}
+	}
+	return true
+}
+
+// located is an entity and the line it was found at.
+type located struct {
+	entity Entity
+	line   int
+}
+
+// loadEntityFile returns the valid entities of the file at path, and
+// errors for the others.
+func loadEntityFile(path string, decode func(*Entity) error) ([]located, FileErrors) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, FileErrors{{Path: path, Err: err}}
+	}
+	var (
+		entities []located
+		errs     FileErrors
+	)
+	add := func(line int, entity Entity, err error) {
+		if err == nil && decode != nil {
+			err = decode(&entity)
+		}
+		if err != nil {
+			errs = append(errs, &FileError{Path: path, Line: line, Err: err})
+			return
+		}
+		entities = append(entities, located{entity: entity, line: line})
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = loadJSON(data, add)
+	case ".ndjson", ".jsonl":
+		err = loadNDJSON(data, add)
+	default:
+		err = loadYAML(data, add)
+	}
+	if err != nil {
+		var fileErr *FileError
+		if errors.As(err, &fileErr) {
+			fileErr.Path = path
+			errs = append(errs, fileErr)
+		} else {
+			errs = append(errs, &FileError{Path: path, Err: err})
+		}
+	}
+	return entities, errs
+}
+
+func loadJSON(data []byte, add func(int, Entity, error)) error {
+	start := skipJSONSpace(data, 0)
+	if start == len(data) {
+		return nil
+	}
+	if data[start] != '[' {
+		var raw json.RawMessage
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return jsonError(data, 0, err)
+		}
+		entity, err := decodeEntity(raw)
+		add(lineAt(data, start), entity, err)
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if _, err := dec.Token(); err != nil {
+		return jsonError(data, dec.InputOffset(), err)
+	}
+	for dec.More() {
+		line := lineAt(data, skipJSONSpace(data, int(dec.InputOffset())))
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return jsonError(data, dec.InputOffset(), err)
+		}
+		entity, err := decodeEntity(raw)
+		add(line, entity, err)
+	}
+	if _, err := dec.Token(); err != nil {
+		return jsonError(data, dec.InputOffset(), err)
+	}
+	return nil
+}
+
+func loadNDJSON(data []byte, add func(int, Entity, error)) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for line := 1; scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		entity, err := decodeEntity(text)
+		add(line, entity, err)
+	}
+	return scanner.Err()
+}
+
+// yamlEntity is the YAML form of an Entity.
+type yamlEntity struct {
+	Identifier string                 `yaml:"identifier"`
+	Properties map[string]string      `yaml:"properties"`
+	Values     map[string]interface{} `yaml:"values"`
+}
+
+func loadYAML(data []byte, add func(int, Entity, error)) error {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document yaml.Node
+		if err := dec.Decode(&document); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if len(document.Content) == 0 {
+			continue
+		}
+		root := document.Content[0]
+		nodes := []*yaml.Node{root}
+		if root.Kind == yaml.SequenceNode {
+			nodes = root.Content
+		}
+		for _, node := range nodes {
+			entity, err := decodeYAMLEntity(node)
+			add(node.Line, entity, err)
+		}
+	}
+}
+
+func decodeYAMLEntity(node *yaml.Node) (Entity, error) {
+	if node.Kind != yaml.MappingNode {
+		return Entity{}, fmt.Errorf("expected an entity, found %s", node.ShortTag())
+	}
+	for i := 0; i < len(node.Content); i += 2 {
+		if err := checkEntityField(node.Content[i].Value); err != nil {
+			return Entity{}, err
+		}
+	}
+	var decoded yamlEntity
+	if err := node.Decode(&decoded); err != nil {
+		return Entity{}, err
+	}
+	data, err := json.Marshal(map[string]interface{}{
+		"identifier": decoded.Identifier,
+		"properties": decoded.Properties,
+		"values":     decoded.Values,
+	})
+	if err != nil {
+		return Entity{}, err
+	}
+	return decodeEntity(data)
+}
+
+// decodeEntity decodes and validates the JSON encoding of an Entity.
+func decodeEntity(data []byte) (Entity, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return Entity{}, fmt.Errorf("expected an entity: %w", err)
+	}
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := checkEntityField(name); err != nil {
+			return Entity{}, err
+		}
+	}
+	var entity Entity
+	if err := json.Unmarshal(data, &entity); err != nil {
+		return Entity{}, err
+	}
+	if entity.ID == "" {
+		return Entity{}, fmt.Errorf("entity has no identifier")
+	}
+	return entity, nil
+}
+
+func checkEntityField(name string) error {
+	switch name {
+	case "identifier", "properties", "values":
+		return nil
+	}
+	return fmt.Errorf("unknown entity field %q", name)
+}
+
+// jsonError returns err as a FileError at the line of the offset it
+// reports, or at the given offset if it reports none.
+func jsonError(data []byte, offset int64, err error) error {
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		offset = syntaxErr.Offset
+	}
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	return &FileError{Line: lineAt(data, int(offset)), Err: err}
+}
+
+// skipJSONSpace returns the offset of the first byte from offset on
+// that isn't whitespace or a separator between array elements.
+func skipJSONSpace(data []byte, offset int) int {
+	for offset < len(data) {
+		switch data[offset] {
+		case ' ', '\t', '\r', '\n', ',':
+			offset++
+		default:
+			return offset
+		}
+	}
+	return offset
+}
+
+// lineAt returns the line of data the byte at offset is on.
+func lineAt(data []byte, offset int) int {
+	return 1 + bytes.Count(data[:offset], []byte{'\n'})
+}
@@ -0,0 +1,144 @@
+package input_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+var _ = Describe("FileEntitySource", func() {
+	var dir string
+
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		Expect(os.MkdirAll(filepath.Dir(path), 0o755)).To(Succeed())
+		Expect(os.WriteFile(path, []byte(content), 0o644)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		dir = GinkgoT().TempDir()
+	})
+
+	It("should load entities from JSON, NDJSON and YAML files", func() {
+		write("single.json", `{"identifier": "a", "properties": {"package": "etcd"}}`)
+		write("list.json", `[
+  {"identifier": "b", "properties": {"package": "etcd"}},
+  {"identifier": "c", "values": {"replicas": 3}}
+]`)
+		write("lines.ndjson", "{\"identifier\": \"d\"}\n\n{\"identifier\": \"e\"}\n")
+		write("nested/catalog.yaml", `identifier: f
+properties:
+  version: 1.0
+---
+- identifier: g
+  properties:
+    enabled: true
+`)
+		write("ignored.txt", `not an entity`)
+		write(".hidden/h.json", `{"identifier": "h"}`)
+
+		source, err := input.NewFileEntitySource(dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		var ids []deppy.Identifier
+		Expect(source.Iterate(context.Background(), func(entity *input.Entity) error {
+			ids = append(ids, entity.ID)
+			return nil
+		})).To(Succeed())
+		Expect(ids).To(Equal([]deppy.Identifier{"a", "b", "c", "d", "e", "f", "g"}))
+
+		Expect(source.Get(context.Background(), "f").Properties).To(Equal(map[string]string{"version": "1.0"}))
+		Expect(source.Get(context.Background(), "g").Properties).To(Equal(map[string]string{"enabled": "true"}))
+		replicas, ok := source.Get(context.Background(), "c").IntProperty("replicas")
+		Expect(ok).To(BeTrue())
+		Expect(replicas).To(Equal(int64(3)))
+	})
+
+	It("should report every invalid entity with its file and line", func() {
+		write("a.json", `[
+  {"identifier": "a"},
+  {"properties": {}}
+]`)
+		write("b.ndjson", "{\"identifier\": \"b\"}\n{\"identifier\": \"a\"}\n{\"identifier\": \n")
+		write("c.yaml", `- identifier: c
+- identifier: d
+  labels: {}
+`)
+
+		_, err := input.LoadEntities(dir)
+		var errs input.FileErrors
+		Expect(errors.As(err, &errs)).To(BeTrue())
+		Expect(errs).To(HaveLen(4))
+		Expect(errs[0].Error()).To(Equal(filepath.Join(dir, "a.json") + ":3: entity has no identifier"))
+		Expect(errs[1].Error()).To(Equal(filepath.Join(dir, "b.ndjson") + `:2: duplicate identifier "a", first defined at ` + filepath.Join(dir, "a.json") + ":2"))
+		Expect(errs[2].Error()).To(HavePrefix(filepath.Join(dir, "b.ndjson") + ":3: expected an entity: "))
+		Expect(errs[3].Error()).To(Equal(filepath.Join(dir, "c.yaml") + `:2: unknown entity field "labels"`))
+	})
+
+	It("should report JSON syntax errors with their line", func() {
+		write("a.json", "[\n  {\"identifier\": \"a\"}\n  {\"identifier\": \"b\"}\n]")
+		_, err := input.LoadEntities(dir)
+		Expect(err).To(MatchError(HavePrefix(filepath.Join(dir, "a.json") + ":3: ")))
+	})
+
+	It("should keep its entities when reloading fails", func() {
+		write("a.json", `{"identifier": "a"}`)
+		source, err := input.NewFileEntitySource(dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		write("b.json", `{}`)
+		Expect(source.Reload()).To(MatchError(filepath.Join(dir, "b.json") + ":1: entity has no identifier"))
+		Expect(source.Get(context.Background(), "a")).NotTo(BeNil())
+
+		write("b.json", `{"identifier": "b"}`)
+		Expect(source.Reload()).To(Succeed())
+		Expect(source.Get(context.Background(), "b")).NotTo(BeNil())
+	})
+
+	It("should apply its entity decoder to every entity it loads", func() {
+		write("a.ndjson", "{\"identifier\": \"a\", \"properties\": {\"replicas\": \"3\"}}\n{\"identifier\": \"b\", \"properties\": {\"replicas\": \"x\"}}\n")
+		decode := func(entity *input.Entity) error {
+			s, _ := entity.StringProperty("replicas")
+			value, err := input.ParseValue(input.IntKind, s)
+			if err != nil {
+				return err
+			}
+			entity.SetValue("replicas", value)
+			return nil
+		}
+		_, err := input.NewFileEntitySource(dir, input.WithEntityDecoder(decode))
+		Expect(err).To(MatchError(HavePrefix(filepath.Join(dir, "a.ndjson") + ":2: ")))
+
+		write("a.ndjson", "{\"identifier\": \"a\", \"properties\": {\"replicas\": \"3\"}}\n")
+		source, err := input.NewFileEntitySource(dir, input.WithEntityDecoder(decode))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(source.Get(context.Background(), "a").Values).To(Equal(map[string]input.Value{"replicas": input.IntValue(3)}))
+	})
+
+	It("should reload when files change while polling", func() {
+		write("a.json", `{"identifier": "a"}`)
+		source, err := input.NewFileEntitySource(dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		reloads := make(chan error, 10)
+		go source.Poll(ctx, 10*time.Millisecond, func(err error) { reloads <- err })
+
+		write("b.yaml", `identifier: b`)
+		Eventually(reloads).Should(Receive(BeNil()))
+		Expect(source.Get(context.Background(), "b")).NotTo(BeNil())
+
+		Expect(os.Remove(filepath.Join(dir, "a.json"))).To(Succeed())
+		Eventually(reloads).Should(Receive(BeNil()))
+		Expect(source.Get(context.Background(), "a")).To(BeNil())
+	})
+})
@@ -0,0 +1,309 @@
+package input
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+// The HTTP protocol of remote entity sources has two endpoints,
+// relative to a base URL:
+//
+//   - GET entities/{id} returns the JSON encoding of the entity with the
+//     given path-escaped Identifier, or 404 if there is none;
+//   - GET entities returns a page of entities in Identifier order, as a
+//     JSON object with the fields "entities" and, if there are more,
+//     "continue". The parameters are "limit", the maximum number of
+//     entities in the page, "continue", the value of "continue" of the
+//     previous page, and "query", a query as accepted by CompileQuery
+//     that entities must match.
+//
+// Errors are returned with a non-2xx status and a JSON object whose
+// "error" field holds the message.
+
+const (
+	entitiesPath    = "entities"
+	defaultPageSize = 500
+	maxPageSize     = 5000
+)
+
+// entityPage is the response body of the entities endpoint.
+type entityPage struct {
+	Entities []Entity `json:"entities"`
+	Continue string   `json:"continue,omitempty"`
+}
+
+// errorBody is the response body of failed requests.
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+// RemoteError is an error returned by a remote entity source.
+type RemoteError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *RemoteError) Error() string {
+	return fmt.Sprintf("remote entity source returned %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+var (
+	_ EntitySource = &HTTPEntitySource{}
+	_ EntityGetter = &HTTPEntitySource{}
+)
+
+// HTTPEntitySource is an EntitySource client for a remote entity source
+// served over HTTP, for instance by EntitySourceHandler. Entities are
+// fetched page by page as they are iterated over, so that Filter,
+// GroupBy and Iterate don't hold the whole remote source in memory
+// before returning. Predicates and grouping functions are evaluated
+// locally; use FilterQuery to filter entities on the server.
+type HTTPEntitySource struct {
+	base     *url.URL
+	client   *http.Client
+	pageSize int
+}
+
+// HTTPOption configures an HTTPEntitySource.
+type HTTPOption func(s *HTTPEntitySource)
+
+// WithHTTPClient sets the client used to send requests. It defaults to
+// http.DefaultClient.
+func WithHTTPClient(client *http.Client) HTTPOption {
+	return func(s *HTTPEntitySource) {
+		s.client = client
+	}
+}
+
+// WithPageSize sets the number of entities requested per page.
+func WithPageSize(n int) HTTPOption {
+	return func(s *HTTPEntitySource) {
+		s.pageSize = n
+	}
+}
+
+// NewHTTPEntitySource returns an HTTPEntitySource for the remote
+// entity source at baseURL.
+func NewHTTPEntitySource(baseURL string, options ...HTTPOption) (*HTTPEntitySource, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid entity source URL: %w", err)
+	}
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+		base.RawPath = ""
+	}
+	s := &HTTPEntitySource{
+		base:     base,
+		client:   http.DefaultClient,
+		pageSize: defaultPageSize,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	if s.pageSize <= 0 || s.pageSize > maxPageSize {
+		return nil, fmt.Errorf("page size must be between 1 and %d", maxPageSize)
+	}
+	return s, nil
+}
+
+// Get returns the entity with the given Identifier, or nil if there is
+// none or it can't be fetched. Use GetEntity to tell these apart.
+func (s *HTTPEntitySource) Get(ctx context.Context, id deppy.Identifier) *Entity {
+	entity, err := s.GetEntity(ctx, id)
+	if err != nil {
+		return nil
+	}
+	return entity
+}
+
+// GetEntity returns the entity with the given Identifier, a
+// NotFoundError if the remote entity source has none, or the error
+// that prevented fetching it.
+func (s *HTTPEntitySource) GetEntity(ctx context.Context, id deppy.Identifier) (*Entity, error) {
+	u := *s.base
+	u.Path = s.base.Path + entitiesPath + "/" + string(id)
+	// Identifiers may contain slashes, which must stay escaped
+	u.RawPath = s.base.EscapedPath() + entitiesPath + "/" + url.PathEscape(string(id))
+	body, err := s.get(ctx, &u)
+	if err != nil {
+		var remoteErr *RemoteError
+		if errors.As(err, &remoteErr) && remoteErr.StatusCode == http.StatusNotFound {
+			return nil, NotFoundError(id)
+		}
+		return nil, err
+	}
+	defer body.Close()
+	var entity Entity
+	if err := json.NewDecoder(body).Decode(&entity); err != nil {
+		return nil, fmt.Errorf("error decoding entity %q: %w", id, err)
+	}
+	return &entity, nil
+}
+
+func (s *HTTPEntitySource) Filter(ctx context.Context, filter Predicate) (EntityList, error) {
+	resultSet := EntityList{}
+	err := s.Iterate(ctx, func(entity *Entity) error {
+		if filter(entity) {
+			resultSet = append(resultSet, *entity)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resultSet, nil
+}
+
+// FilterQuery returns the entities matching query, which is evaluated
+// by the remote entity source.
+func (s *HTTPEntitySource) FilterQuery(ctx context.Context, query string) (EntityList, error) {
+	resultSet := EntityList{}
+	err := s.iterate(ctx, query, func(entity *Entity) error {
+		resultSet = append(resultSet, *entity)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resultSet, nil
+}
+
+func (s *HTTPEntitySource) GroupBy(ctx context.Context, fn GroupByFunction) (EntityListMap, error) {
+	resultSet := EntityListMap{}
+	err := s.Iterate(ctx, func(entity *Entity) error {
+		for _, key := range fn(entity) {
+			resultSet[key] = append(resultSet[key], *entity)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resultSet, nil
+}
+
+// Iterate calls fn with every entity of the remote entity source, in
+// Identifier order, fetching the next page only once fn has been
+// called with every entity of the previous one.
+func (s *HTTPEntitySource) Iterate(ctx context.Context, fn IteratorFunction) error {
+	return s.iterate(ctx, "", fn)
+}
+
+func (s *HTTPEntitySource) iterate(ctx context.Context, query string, fn IteratorFunction) error {
+	token := ""
+	for {
+		u := s.base.JoinPath(entitiesPath)
+		params := url.Values{"limit": {strconv.Itoa(s.pageSize)}}
+		if token != "" {
+			params.Set("continue", token)
+		}
+		if query != "" {
+			params.Set("query", query)
+		}
+		u.RawQuery = params.Encode()
+		next, err := s.page(ctx, u, fn)
+		if err != nil {
+			return err
+		}
+		if next == "" {
+			return nil
+		}
+		token = next
+	}
+}
+
+// page calls fn with the entities of the page at u as they are decoded,
+// and returns its continue token.
+func (s *HTTPEntitySource) page(ctx context.Context, u *url.URL, fn IteratorFunction) (string, error) {
+	body, err := s.get(ctx, u)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	dec := json.NewDecoder(body)
+	if err := expectDelim(dec, '{'); err != nil {
+		return "", err
+	}
+	var token string
+	for dec.More() {
+		key, err := dec.Token()
+		if err != nil {
+			return "", fmt.Errorf("error decoding entity page: %w", err)
+		}
+		switch key {
+		case "entities":
+			if err := expectDelim(dec, '['); err != nil {
+				return "", err
+			}
+			for dec.More() {
+				if err := ctx.Err(); err != nil {
+					return "", err
+				}
+				var entity Entity
+				if err := dec.Decode(&entity); err != nil {
+					return "", fmt.Errorf("error decoding entity page: %w", err)
+				}
+				if err := fn(&entity); err != nil {
+					return "", err
+				}
+			}
+			if err := expectDelim(dec, ']'); err != nil {
+				return "", err
+			}
+		case "continue":
+			if err := dec.Decode(&token); err != nil {
+				return "", fmt.Errorf("error decoding entity page: %w", err)
+			}
+		default:
+			var ignored json.RawMessage
+			if err := dec.Decode(&ignored); err != nil {
+				return "", fmt.Errorf("error decoding entity page: %w", err)
+			}
+		}
+	}
+	return token, expectDelim(dec, '}')
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("error decoding entity page: %w", err)
+	}
+	if t != delim {
+		return fmt.Errorf("error decoding entity page: expected %q, found %v", delim, t)
+	}
+	return nil
+}
+
+// get sends a GET request for u, and returns the response body if it
+// succeeds.
+func (s *HTTPEntitySource) get(ctx context.Context, u *url.URL) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		var body errorBody
+		if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<16)).Decode(&body); err != nil || body.Error == "" {
+			body.Error = http.StatusText(resp.StatusCode)
+		}
+		return nil, &RemoteError{StatusCode: resp.StatusCode, Message: body.Error}
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,138 @@
+package input_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+var _ = Describe("HTTPEntitySource", func() {
+	var (
+		ctx      context.Context
+		server   *httptest.Server
+		requests int32
+		source   *input.HTTPEntitySource
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		entities := map[deppy.Identifier]input.Entity{}
+		for i := 0; i < 25; i++ {
+			id := deppy.Identifier(fmt.Sprintf("catalog/entity-%02d", i))
+			entities[id] = *input.NewEntity(id, map[string]string{
+				"index":  fmt.Sprint(i),
+				"parity": []string{"even", "odd"}[i%2],
+			})
+		}
+		handler := http.StripPrefix("/api", input.NewEntitySourceHandler(input.NewCacheQuerier(entities)))
+		requests = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			handler.ServeHTTP(w, r)
+		}))
+		DeferCleanup(server.Close)
+
+		var err error
+		source, err = input.NewHTTPEntitySource(server.URL+"/api", input.WithPageSize(10))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should reject invalid page sizes", func() {
+		_, err := input.NewHTTPEntitySource(server.URL, input.WithPageSize(0))
+		Expect(err).To(MatchError("page size must be between 1 and 5000"))
+	})
+
+	It("should get entities by identifier", func() {
+		entity := source.Get(ctx, "catalog/entity-07")
+		Expect(entity).To(Equal(input.NewEntity("catalog/entity-07", map[string]string{"index": "7", "parity": "odd"})))
+		Expect(source.Get(ctx, "catalog/missing")).To(BeNil())
+	})
+
+	It("should iterate over every page in identifier order", func() {
+		var ids []deppy.Identifier
+		Expect(source.Iterate(ctx, func(entity *input.Entity) error {
+			ids = append(ids, entity.ID)
+			return nil
+		})).To(Succeed())
+		Expect(ids).To(HaveLen(25))
+		Expect(ids[0]).To(Equal(deppy.Identifier("catalog/entity-00")))
+		Expect(ids[24]).To(Equal(deppy.Identifier("catalog/entity-24")))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(3)))
+	})
+
+	It("should only fetch the pages it needs", func() {
+		stop := errors.New("stop")
+		Expect(source.Iterate(ctx, func(entity *input.Entity) error {
+			if entity.ID == "catalog/entity-05" {
+				return stop
+			}
+			return nil
+		})).To(BeIdenticalTo(stop))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+	})
+
+	It("should filter and group entities", func() {
+		el, err := source.Filter(ctx, func(entity *input.Entity) bool {
+			return entity.Properties["parity"] == "odd"
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(el).To(HaveLen(12))
+
+		grouped, err := source.GroupBy(ctx, func(entity *input.Entity) []string {
+			return []string{entity.Properties["parity"]}
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(grouped["even"]).To(HaveLen(13))
+		Expect(grouped["odd"]).To(HaveLen(12))
+	})
+
+	It("should filter entities on the server", func() {
+		el, err := source.FilterQuery(ctx, `int(index) >= 20 && parity == "even"`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(el.CollectIds()).To(Equal([]deppy.Identifier{"catalog/entity-20", "catalog/entity-22", "catalog/entity-24"}))
+		Expect(atomic.LoadInt32(&requests)).To(Equal(int32(1)))
+	})
+
+	It("should return errors reported by the server", func() {
+		_, err := source.FilterQuery(ctx, `index ==`)
+		var remoteErr *input.RemoteError
+		Expect(errors.As(err, &remoteErr)).To(BeTrue())
+		Expect(remoteErr.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(err).To(MatchError(HavePrefix("remote entity source returned 400 Bad Request: ")))
+	})
+
+	It("should stop when the context is cancelled", func() {
+		cancelled, cancel := context.WithCancel(ctx)
+		err := source.Iterate(cancelled, func(entity *input.Entity) error {
+			cancel()
+			return nil
+		})
+		Expect(err).To(MatchError(context.Canceled))
+	})
+
+	It("should reject requests it doesn't serve", func() {
+		resp, err := http.Post(server.URL+"/api/entities", "application/json", nil)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusMethodNotAllowed))
+
+		resp, err = http.Get(server.URL + "/api/other")
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+
+		resp, err = http.Get(server.URL + "/api/entities?limit=0")
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+	})
+})
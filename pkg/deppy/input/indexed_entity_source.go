@@ -0,0 +1,220 @@
+package input
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+var (
+	_ EntitySource   = &IndexedEntitySource{}
+	_ PropertyLookup = &IndexedEntitySource{}
+)
+
+// PropertyLookup is implemented by EntitySources that can find entities
+// by the value of a property without evaluating a Predicate against
+// every entity.
+type PropertyLookup interface {
+	EntitySource
+	Lookup(ctx context.Context, key, value string) (EntityList, error)
+	GroupByProperty(ctx context.Context, key string) (EntityListMap, error)
+}
+
+// IndexedEntitySource is an in-memory EntitySource that maintains
+// secondary indexes on a declared set of property keys, so that
+// entities can be looked up by the value of those properties, or by a
+// prefix of it, without scanning every entity.
+//
+// Entities are held in Identifier order, which is the order of every
+// result returned by the receiver. Callbacks and results get copies of
+// the entities, property maps included, so that modifying them affects
+// neither the receiver nor its indexes. An IndexedEntitySource is
+// immutable and safe for concurrent use.
+type IndexedEntitySource struct {
+	entities []Entity
+	byID     map[deppy.Identifier]int
+	indexes  map[string]*propertyIndex
+}
+
+// propertyIndex maps the values of a property to the positions of the
+// entities that have them.
+type propertyIndex struct {
+	postings map[string][]int
+	// values holds the keys of postings in ascending order, for
+	// prefix lookups.
+	values []string
+}
+
+// NewIndexedEntitySource returns an IndexedEntitySource holding copies
+// of the given entities and indexing the properties with the given
+// keys. Entities are indexed by the string form of their properties.
+func NewIndexedEntitySource(entities map[deppy.Identifier]Entity, keys ...string) *IndexedEntitySource {
+	copied := make(map[deppy.Identifier]Entity, len(entities))
+	for id, entity := range entities {
+		copied[id] = copyEntity(entity)
+	}
+	return indexEntities(copied, keys...)
+}
+
+// indexEntities is NewIndexedEntitySource for entities whose property
+// maps are never modified, which it doesn't copy.
+func indexEntities(entities map[deppy.Identifier]Entity, keys ...string) *IndexedEntitySource {
+	s := &IndexedEntitySource{
+		entities: make([]Entity, 0, len(entities)),
+		byID:     make(map[deppy.Identifier]int, len(entities)),
+		indexes:  make(map[string]*propertyIndex, len(keys)),
+	}
+	for _, entity := range entities {
+		s.entities = append(s.entities, entity)
+	}
+	sort.Slice(s.entities, func(i, j int) bool {
+		return s.entities[i].ID < s.entities[j].ID
+	})
+	for i := range s.entities {
+		s.byID[s.entities[i].ID] = i
+	}
+	for _, key := range keys {
+		if _, ok := s.indexes[key]; ok {
+			continue
+		}
+		index := &propertyIndex{postings: map[string][]int{}}
+		for i := range s.entities {
+			if value, ok := s.entities[i].StringProperty(key); ok {
+				index.postings[value] = append(index.postings[value], i)
+			}
+		}
+		index.values = make([]string, 0, len(index.postings))
+		for value := range index.postings {
+			index.values = append(index.values, value)
+		}
+		sort.Strings(index.values)
+		s.indexes[key] = index
+	}
+	return s
+}
+
+// Indexed returns true if the property with the given key is indexed.
+func (s *IndexedEntitySource) Indexed(key string) bool {
+	_, ok := s.indexes[key]
+	return ok
+}
+
+func (s *IndexedEntitySource) Get(_ context.Context, id deppy.Identifier) *Entity {
+	if i, ok := s.byID[id]; ok {
+		entity := copyEntity(s.entities[i])
+		return &entity
+	}
+	return nil
+}
+
+func (s *IndexedEntitySource) Filter(ctx context.Context, filter Predicate) (EntityList, error) {
+	resultSet := EntityList{}
+	for i := range s.entities {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		entity := copyEntity(s.entities[i])
+		if filter(&entity) {
+			resultSet = append(resultSet, entity)
+		}
+	}
+	return resultSet, nil
+}
+
+func (s *IndexedEntitySource) GroupBy(ctx context.Context, fn GroupByFunction) (EntityListMap, error) {
+	resultSet := EntityListMap{}
+	for i := range s.entities {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		entity := copyEntity(s.entities[i])
+		for _, key := range fn(&entity) {
+			resultSet[key] = append(resultSet[key], entity)
+		}
+	}
+	return resultSet, nil
+}
+
+func (s *IndexedEntitySource) Iterate(ctx context.Context, fn IteratorFunction) error {
+	for i := range s.entities {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		entity := copyEntity(s.entities[i])
+		if err := fn(&entity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lookup returns the entities whose property with the given key has
+// the given value. Lookups on keys that aren't indexed scan every
+// entity.
+func (s *IndexedEntitySource) Lookup(ctx context.Context, key, value string) (EntityList, error) {
+	index, ok := s.indexes[key]
+	if !ok {
+		return s.Filter(ctx, func(entity *Entity) bool {
+			v, ok := entity.StringProperty(key)
+			return ok && v == value
+		})
+	}
+	return s.collect(index.postings[value]), nil
+}
+
+// LookupPrefix returns the entities whose property with the given key
+// has a value starting with prefix. Lookups on keys that aren't
+// indexed scan every entity.
+func (s *IndexedEntitySource) LookupPrefix(ctx context.Context, key, prefix string) (EntityList, error) {
+	index, ok := s.indexes[key]
+	if !ok {
+		return s.Filter(ctx, func(entity *Entity) bool {
+			v, ok := entity.StringProperty(key)
+			return ok && strings.HasPrefix(v, prefix)
+		})
+	}
+	start := sort.SearchStrings(index.values, prefix)
+	end := start
+	for end < len(index.values) && strings.HasPrefix(index.values[end], prefix) {
+		end++
+	}
+	if end-start == 1 {
+		return s.collect(index.postings[index.values[start]]), nil
+	}
+	var positions []int
+	for _, value := range index.values[start:end] {
+		positions = append(positions, index.postings[value]...)
+	}
+	sort.Ints(positions)
+	return s.collect(positions), nil
+}
+
+// GroupByProperty groups entities by the value of their property with
+// the given key. Entities without that property are left out. Keys that
+// aren't indexed require a scan of every entity.
+func (s *IndexedEntitySource) GroupByProperty(ctx context.Context, key string) (EntityListMap, error) {
+	index, ok := s.indexes[key]
+	if !ok {
+		return s.GroupBy(ctx, func(entity *Entity) []string {
+			if v, ok := entity.StringProperty(key); ok {
+				return []string{v}
+			}
+			return nil
+		})
+	}
+	resultSet := make(EntityListMap, len(index.postings))
+	for value, positions := range index.postings {
+		resultSet[value] = s.collect(positions)
+	}
+	return resultSet, nil
+}
+
+func (s *IndexedEntitySource) collect(positions []int) EntityList {
+	resultSet := make(EntityList, len(positions))
+	for i, position := range positions {
+		resultSet[i] = copyEntity(s.entities[position])
+	}
+	return resultSet
+}
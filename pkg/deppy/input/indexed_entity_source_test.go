@@ -0,0 +1,162 @@
+package input_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+var _ = Describe("IndexedEntitySource", func() {
+	var (
+		ctx    context.Context
+		source *input.IndexedEntitySource
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		entities := map[deppy.Identifier]input.Entity{
+			"etcd.v0.9.0":       *input.NewEntity("etcd.v0.9.0", map[string]string{"package": "etcd", "channel": "stable"}),
+			"etcd.v0.9.2":       *input.NewEntity("etcd.v0.9.2", map[string]string{"package": "etcd", "channel": "stable"}),
+			"etcd.v1.0.0":       *input.NewEntity("etcd.v1.0.0", map[string]string{"package": "etcd", "channel": "fast"}),
+			"etcd-proxy.v0.1.0": *input.NewEntity("etcd-proxy.v0.1.0", map[string]string{"package": "etcd-proxy", "channel": "stable"}),
+			"prometheus.v1.0.0": *input.NewEntity("prometheus.v1.0.0", map[string]string{"package": "prometheus"}),
+		}
+		source = input.NewIndexedEntitySource(entities, "package", "channel")
+	})
+
+	It("should report which keys are indexed", func() {
+		Expect(source.Indexed("package")).To(BeTrue())
+		Expect(source.Indexed("version")).To(BeFalse())
+	})
+
+	It("should return requested entity", func() {
+		Expect(source.Get(ctx, "etcd.v1.0.0")).To(Equal(input.NewEntity("etcd.v1.0.0", map[string]string{"package": "etcd", "channel": "fast"})))
+		Expect(source.Get(ctx, "missing")).To(BeNil())
+	})
+
+	It("should look up entities by property value in identifier order", func() {
+		el, err := source.Lookup(ctx, "package", "etcd")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(el.CollectIds()).To(Equal([]deppy.Identifier{"etcd.v0.9.0", "etcd.v0.9.2", "etcd.v1.0.0"}))
+
+		el, err = source.Lookup(ctx, "package", "missing")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(el).To(BeEmpty())
+	})
+
+	It("should look up entities by property value prefix in identifier order", func() {
+		el, err := source.LookupPrefix(ctx, "package", "etcd")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(el.CollectIds()).To(Equal([]deppy.Identifier{"etcd-proxy.v0.1.0", "etcd.v0.9.0", "etcd.v0.9.2", "etcd.v1.0.0"}))
+
+		el, err = source.LookupPrefix(ctx, "channel", "st")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(el.CollectIds()).To(Equal([]deppy.Identifier{"etcd-proxy.v0.1.0", "etcd.v0.9.0", "etcd.v0.9.2"}))
+
+		el, err = source.LookupPrefix(ctx, "channel", "z")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(el).To(BeEmpty())
+	})
+
+	It("should fall back to scanning for keys that aren't indexed", func() {
+		unindexed := input.NewIndexedEntitySource(map[deppy.Identifier]input.Entity{
+			"a": *input.NewEntity("a", map[string]string{"package": "etcd"}),
+			"b": *input.NewEntity("b", map[string]string{"package": "etcd-proxy"}),
+		})
+		el, err := unindexed.Lookup(ctx, "package", "etcd")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(el.CollectIds()).To(Equal([]deppy.Identifier{"a"}))
+
+		el, err = unindexed.LookupPrefix(ctx, "package", "etcd")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(el.CollectIds()).To(Equal([]deppy.Identifier{"a", "b"}))
+
+		grouped, err := unindexed.GroupByProperty(ctx, "package")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(grouped).To(HaveLen(2))
+	})
+
+	It("should group entities by property value", func() {
+		grouped, err := source.GroupByProperty(ctx, "channel")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(grouped).To(HaveLen(2))
+		Expect(grouped["stable"].CollectIds()).To(Equal([]deppy.Identifier{"etcd-proxy.v0.1.0", "etcd.v0.9.0", "etcd.v0.9.2"}))
+		Expect(grouped["fast"].CollectIds()).To(Equal([]deppy.Identifier{"etcd.v1.0.0"}))
+	})
+
+	It("should filter, group and iterate like CacheEntitySource", func() {
+		el, err := source.Filter(ctx, func(entity *input.Entity) bool {
+			return entity.Properties["channel"] == "fast"
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(el.CollectIds()).To(Equal([]deppy.Identifier{"etcd.v1.0.0"}))
+
+		grouped, err := source.GroupBy(ctx, func(entity *input.Entity) []string {
+			return []string{entity.Properties["package"]}
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(grouped["etcd"]).To(HaveLen(3))
+
+		var ids []deppy.Identifier
+		Expect(source.Iterate(ctx, func(entity *input.Entity) error {
+			ids = append(ids, entity.ID)
+			return nil
+		})).To(Succeed())
+		Expect(ids).To(HaveLen(5))
+	})
+
+	It("should pass copies of its entities to callbacks and in results", func() {
+		mutate := func(entity *input.Entity) {
+			entity.Properties["package"] = "changed"
+			entity.SetValue("channel", input.StringValue("changed"))
+		}
+		Expect(source.Iterate(ctx, func(entity *input.Entity) error {
+			mutate(entity)
+			return nil
+		})).To(Succeed())
+		el, err := source.Filter(ctx, func(entity *input.Entity) bool {
+			mutate(entity)
+			return true
+		})
+		Expect(err).NotTo(HaveOccurred())
+		for i := range el {
+			mutate(&el[i])
+		}
+		grouped, err := source.GroupBy(ctx, func(entity *input.Entity) []string {
+			mutate(entity)
+			return []string{"all"}
+		})
+		Expect(err).NotTo(HaveOccurred())
+		mutate(&grouped["all"][0])
+		el, err = source.Lookup(ctx, "package", "prometheus")
+		Expect(err).NotTo(HaveOccurred())
+		mutate(&el[0])
+		mutate(source.Get(ctx, "prometheus.v1.0.0"))
+
+		Expect(source.Get(ctx, "etcd.v1.0.0")).To(Equal(input.NewEntity("etcd.v1.0.0", map[string]string{"package": "etcd", "channel": "fast"})))
+		Expect(source.Get(ctx, "prometheus.v1.0.0")).To(Equal(input.NewEntity("prometheus.v1.0.0", map[string]string{"package": "prometheus"})))
+		el, err = source.Lookup(ctx, "package", "changed")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(el).To(BeEmpty())
+	})
+
+	It("should not share property maps with the entities it is given", func() {
+		properties := map[string]string{"package": "etcd"}
+		source := input.NewIndexedEntitySource(map[deppy.Identifier]input.Entity{
+			"a": *input.NewEntity("a", properties),
+		}, "package")
+		properties["package"] = "changed"
+		Expect(source.Get(ctx, "a").Properties).To(HaveKeyWithValue("package", "etcd"))
+	})
+
+	It("should stop when the context is done", func() {
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := source.Filter(cancelled, func(*input.Entity) bool { return true })
+		Expect(err).To(MatchError(context.Canceled))
+	})
+})
@@ -0,0 +1,251 @@
+package input
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+// KeyedEntitySource is an EntitySource that can recognize repeated
+// queries by a key naming them. Two queries with the same key must
+// return the same results when run against the same entities.
+type KeyedEntitySource interface {
+	EntitySource
+	FilterKeyed(ctx context.Context, key string, filter Predicate) (EntityList, error)
+	GroupByKeyed(ctx context.Context, key string, fn GroupByFunction) (EntityListMap, error)
+}
+
+// FilterByKey filters the entities of source with filter, named by key
+// if source is a KeyedEntitySource.
+func FilterByKey(ctx context.Context, source EntitySource, key string, filter Predicate) (EntityList, error) {
+	if keyed, ok := source.(KeyedEntitySource); ok {
+		return keyed.FilterKeyed(ctx, key, filter)
+	}
+	return source.Filter(ctx, filter)
+}
+
+// GroupByKey groups the entities of source with fn, named by key if
+// source is a KeyedEntitySource.
+func GroupByKey(ctx context.Context, source EntitySource, key string, fn GroupByFunction) (EntityListMap, error) {
+	if keyed, ok := source.(KeyedEntitySource); ok {
+		return keyed.GroupByKeyed(ctx, key, fn)
+	}
+	return source.GroupBy(ctx, fn)
+}
+
+// MemoStats counts the queries answered by a MemoizingEntitySource
+// from its memo (hits) and from the wrapped source (misses).
+type MemoStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// MemoOption configures a MemoizingEntitySource.
+type MemoOption func(m *MemoizingEntitySource)
+
+// WithTTL sets how long results are memoized for. By default, they are
+// kept until invalidated.
+func WithTTL(ttl time.Duration) MemoOption {
+	return func(m *MemoizingEntitySource) {
+		m.ttl = ttl
+	}
+}
+
+// WithClock sets the function returning the current time, which
+// defaults to time.Now.
+func WithClock(now func() time.Time) MemoOption {
+	return func(m *MemoizingEntitySource) {
+		m.now = now
+	}
+}
+
+// memoKey identifies a memoized query. Filter and GroupBy queries
+// have separate key spaces.
+type memoKey struct {
+	groupBy bool
+	key     string
+}
+
+type memoEntry struct {
+	list    EntityList
+	groups  EntityListMap
+	expires time.Time
+}
+
+var (
+	_ KeyedEntitySource = &MemoizingEntitySource{}
+	_ EntityGetter      = &MemoizingEntitySource{}
+)
+
+// MemoizingEntitySource wraps an EntitySource and memoizes the results
+// of keyed queries, i.e. those made with FilterKeyed, GroupByKeyed and
+// FilterQuery. Other calls go to the wrapped source.
+//
+// Memoized results are valid as long as the wrapped source doesn't
+// change. Wrapping the source once per resolution shares results
+// between the variable sources of that resolution; for sources wrapped
+// for longer, use a TTL or invalidate the results when the source
+// changes. Callers receive their own copy of every result, which they
+// may sort or modify.
+type MemoizingEntitySource struct {
+	source EntitySource
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu    sync.Mutex
+	memo  map[memoKey]memoEntry
+	stats MemoStats
+	// generation is incremented by invalidations, so that results of
+	// queries started before them aren't memoized.
+	generation uint64
+}
+
+// NewMemoizingEntitySource returns a MemoizingEntitySource wrapping
+// source.
+func NewMemoizingEntitySource(source EntitySource, options ...MemoOption) *MemoizingEntitySource {
+	m := &MemoizingEntitySource{
+		source: source,
+		now:    time.Now,
+		memo:   map[memoKey]memoEntry{},
+	}
+	for _, option := range options {
+		option(m)
+	}
+	return m
+}
+
+func (m *MemoizingEntitySource) Get(ctx context.Context, id deppy.Identifier) *Entity {
+	return m.source.Get(ctx, id)
+}
+
+// GetEntity returns the entity with the given Identifier, as returned
+// by the wrapped source adapted to EntitySourceV2.
+func (m *MemoizingEntitySource) GetEntity(ctx context.Context, id deppy.Identifier) (*Entity, error) {
+	return AsEntitySourceV2(m.source).Get(ctx, id)
+}
+
+func (m *MemoizingEntitySource) Filter(ctx context.Context, filter Predicate) (EntityList, error) {
+	return m.source.Filter(ctx, filter)
+}
+
+func (m *MemoizingEntitySource) GroupBy(ctx context.Context, fn GroupByFunction) (EntityListMap, error) {
+	return m.source.GroupBy(ctx, fn)
+}
+
+func (m *MemoizingEntitySource) Iterate(ctx context.Context, fn IteratorFunction) error {
+	return m.source.Iterate(ctx, fn)
+}
+
+// FilterKeyed returns the memoized result of the Filter query named by
+// key, running it if there is none.
+func (m *MemoizingEntitySource) FilterKeyed(ctx context.Context, key string, filter Predicate) (EntityList, error) {
+	entry, generation, ok := m.lookup(memoKey{key: key})
+	if ok {
+		return copyEntityList(entry.list), nil
+	}
+	list, err := m.source.Filter(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	m.store(memoKey{key: key}, generation, memoEntry{list: copyEntityList(list)})
+	return list, nil
+}
+
+// GroupByKeyed returns the memoized result of the GroupBy query named
+// by key, running it if there is none.
+func (m *MemoizingEntitySource) GroupByKeyed(ctx context.Context, key string, fn GroupByFunction) (EntityListMap, error) {
+	entry, generation, ok := m.lookup(memoKey{groupBy: true, key: key})
+	if ok {
+		return copyEntityListMap(entry.groups), nil
+	}
+	groups, err := m.source.GroupBy(ctx, fn)
+	if err != nil {
+		return nil, err
+	}
+	m.store(memoKey{groupBy: true, key: key}, generation, memoEntry{groups: copyEntityListMap(groups)})
+	return groups, nil
+}
+
+// FilterQuery returns the entities matching query, as compiled by
+// CompileQuery. Results are memoized under the text of the query.
+func (m *MemoizingEntitySource) FilterQuery(ctx context.Context, query string) (EntityList, error) {
+	filter, err := CompileQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	return m.FilterKeyed(ctx, "query:"+query, filter)
+}
+
+// Invalidate discards every memoized result.
+func (m *MemoizingEntitySource) Invalidate() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.memo = map[memoKey]memoEntry{}
+	m.generation++
+}
+
+// InvalidateKey discards the memoized results of the queries named by
+// key.
+func (m *MemoizingEntitySource) InvalidateKey(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.memo, memoKey{key: key})
+	delete(m.memo, memoKey{groupBy: true, key: key})
+	m.generation++
+}
+
+// Stats returns the number of keyed queries answered with and without
+// a memoized result so far.
+func (m *MemoizingEntitySource) Stats() MemoStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.stats
+}
+
+func (m *MemoizingEntitySource) lookup(key memoKey) (memoEntry, uint64, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.memo[key]
+	if ok && !entry.expires.IsZero() && !m.now().Before(entry.expires) {
+		delete(m.memo, key)
+		ok = false
+	}
+	if ok {
+		m.stats.Hits++
+	} else {
+		m.stats.Misses++
+	}
+	return entry, m.generation, ok
+}
+
+func (m *MemoizingEntitySource) store(key memoKey, generation uint64, entry memoEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if generation != m.generation {
+		return
+	}
+	if m.ttl > 0 {
+		entry.expires = m.now().Add(m.ttl)
+	}
+	m.memo[key] = entry
+}
+
+func copyEntityList(list EntityList) EntityList {
+	if list == nil {
+		return nil
+	}
+	return append(make(EntityList, 0, len(list)), list...)
+}
+
+func copyEntityListMap(groups EntityListMap) EntityListMap {
+	if groups == nil {
+		return nil
+	}
+	copied := make(EntityListMap, len(groups))
+	for key, list := range groups {
+		copied[key] = copyEntityList(list)
+	}
+	return copied
+}
@@ -0,0 +1,152 @@
+package input_test
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+// countingEntitySource counts the queries that reach it.
+type countingEntitySource struct {
+	input.EntitySource
+	filters, groupBys int32
+}
+
+func (c *countingEntitySource) Filter(ctx context.Context, filter input.Predicate) (input.EntityList, error) {
+	atomic.AddInt32(&c.filters, 1)
+	return c.EntitySource.Filter(ctx, filter)
+}
+
+func (c *countingEntitySource) GroupBy(ctx context.Context, fn input.GroupByFunction) (input.EntityListMap, error) {
+	atomic.AddInt32(&c.groupBys, 1)
+	return c.EntitySource.GroupBy(ctx, fn)
+}
+
+var _ = Describe("MemoizingEntitySource", func() {
+	var (
+		ctx      context.Context
+		counting *countingEntitySource
+		now      time.Time
+		source   *input.MemoizingEntitySource
+	)
+
+	byParity := func(parity string) input.Predicate {
+		return func(entity *input.Entity) bool {
+			return entity.Properties["parity"] == parity
+		}
+	}
+	parity := func(entity *input.Entity) []string {
+		return []string{entity.Properties["parity"]}
+	}
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		counting = &countingEntitySource{EntitySource: input.NewIndexedEntitySource(map[deppy.Identifier]input.Entity{
+			"1": *input.NewEntity("1", map[string]string{"parity": "odd"}),
+			"2": *input.NewEntity("2", map[string]string{"parity": "even"}),
+			"3": *input.NewEntity("3", map[string]string{"parity": "odd"}),
+		})}
+		now = time.Unix(0, 0)
+		source = input.NewMemoizingEntitySource(counting, input.WithTTL(time.Minute), input.WithClock(func() time.Time { return now }))
+	})
+
+	It("should memoize keyed queries", func() {
+		for i := 0; i < 3; i++ {
+			el, err := input.FilterByKey(ctx, source, "odd", byParity("odd"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(el.CollectIds()).To(Equal([]deppy.Identifier{"1", "3"}))
+
+			grouped, err := input.GroupByKey(ctx, source, "parity", parity)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(grouped["odd"]).To(HaveLen(2))
+		}
+		Expect(counting.filters).To(Equal(int32(1)))
+		Expect(counting.groupBys).To(Equal(int32(1)))
+		Expect(source.Stats()).To(Equal(input.MemoStats{Hits: 4, Misses: 2}))
+	})
+
+	It("should keep filter and group by keys apart", func() {
+		_, err := source.FilterKeyed(ctx, "key", byParity("odd"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = source.GroupByKeyed(ctx, "key", parity)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(source.Stats()).To(Equal(input.MemoStats{Misses: 2}))
+	})
+
+	It("should not memoize other queries", func() {
+		for i := 0; i < 2; i++ {
+			_, err := source.Filter(ctx, byParity("odd"))
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(counting.filters).To(Equal(int32(2)))
+		Expect(source.Stats()).To(Equal(input.MemoStats{}))
+	})
+
+	It("should memoize queries by their text", func() {
+		for i := 0; i < 2; i++ {
+			el, err := source.FilterQuery(ctx, `parity == "even"`)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(el.CollectIds()).To(Equal([]deppy.Identifier{"2"}))
+		}
+		Expect(counting.filters).To(Equal(int32(1)))
+
+		_, err := source.FilterQuery(ctx, `parity ==`)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should return copies of memoized results", func() {
+		el, err := source.FilterKeyed(ctx, "odd", byParity("odd"))
+		Expect(err).NotTo(HaveOccurred())
+		el.Sort(func(e1, e2 *input.Entity) bool { return e1.ID > e2.ID })
+
+		el, err = source.FilterKeyed(ctx, "odd", byParity("odd"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(el.CollectIds()).To(Equal([]deppy.Identifier{"1", "3"}))
+	})
+
+	It("should expire results after the TTL", func() {
+		_, err := source.FilterKeyed(ctx, "odd", byParity("odd"))
+		Expect(err).NotTo(HaveOccurred())
+		now = now.Add(59 * time.Second)
+		_, err = source.FilterKeyed(ctx, "odd", byParity("odd"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(counting.filters).To(Equal(int32(1)))
+
+		now = now.Add(time.Second)
+		_, err = source.FilterKeyed(ctx, "odd", byParity("odd"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(counting.filters).To(Equal(int32(2)))
+	})
+
+	It("should discard invalidated results", func() {
+		_, err := source.FilterKeyed(ctx, "odd", byParity("odd"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = source.FilterKeyed(ctx, "even", byParity("even"))
+		Expect(err).NotTo(HaveOccurred())
+
+		source.InvalidateKey("odd")
+		_, err = source.FilterKeyed(ctx, "odd", byParity("odd"))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = source.FilterKeyed(ctx, "even", byParity("even"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(counting.filters).To(Equal(int32(3)))
+
+		source.Invalidate()
+		_, err = source.FilterKeyed(ctx, "even", byParity("even"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(counting.filters).To(Equal(int32(4)))
+	})
+
+	It("should fall back to plain queries for other sources", func() {
+		el, err := input.FilterByKey(ctx, counting, "odd", byParity("odd"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(el).To(HaveLen(2))
+		Expect(counting.filters).To(Equal(int32(1)))
+	})
+})
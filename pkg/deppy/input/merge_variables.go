@@ -0,0 +1,137 @@
+package input
+
+import (
+	"fmt"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+// DuplicateVariable is returned when variables with the same Identifier
+// are merged with the FailOnDuplicate policy.
+type DuplicateVariable deppy.Identifier
+
+func (e DuplicateVariable) Error() string {
+	return fmt.Sprintf("duplicate variable %q", deppy.Identifier(e))
+}
+
+// MergePolicy determines how variables with the same Identifier are
+// combined.
+type MergePolicy int
+
+const (
+	// FailOnDuplicate reports a DuplicateVariable error.
+	FailOnDuplicate MergePolicy = iota
+	// UnionConstraints combines the variables into a MergedVariable
+	// with the constraints of all of them, so that a source can add
+	// constraints to a variable generated by another one.
+	UnionConstraints
+	// LastWins keeps only the last of the variables.
+	LastWins
+)
+
+func (p MergePolicy) String() string {
+	switch p {
+	case FailOnDuplicate:
+		return "FailOnDuplicate"
+	case UnionConstraints:
+		return "UnionConstraints"
+	case LastWins:
+		return "LastWins"
+	}
+	return fmt.Sprintf("MergePolicy(%d)", int(p))
+}
+
+var _ deppy.Variable = &MergedVariable{}
+
+// MergedVariable is the result of merging variables with the
+// UnionConstraints policy. It is the first of the merged variables, with
+// the constraints of all of them in the order they were merged.
+type MergedVariable struct {
+	deppy.Variable
+	variables   []deppy.Variable
+	constraints []deppy.Constraint
+}
+
+func (m *MergedVariable) Constraints() []deppy.Constraint {
+	return m.constraints
+}
+
+// Unwrap returns the first of the merged variables.
+func (m *MergedVariable) Unwrap() deppy.Variable {
+	return m.Variable
+}
+
+// Variables returns every merged variable, in the order they were
+// merged.
+func (m *MergedVariable) Variables() []deppy.Variable {
+	return m.variables
+}
+
+// MergeVariables returns the given variables, with the variables sharing
+// an Identifier combined according to policy. Each resulting variable
+// is at the position of the first variable with its Identifier.
+func MergeVariables(policy MergePolicy, variables []deppy.Variable) ([]deppy.Variable, error) {
+	merger := newVariableMerger(policy, len(variables))
+	for _, variable := range variables {
+		if err := merger.add(variable); err != nil {
+			return nil, err
+		}
+	}
+	return merger.variables, nil
+}
+
+// variableMerger merges variables one at a time.
+type variableMerger struct {
+	policy    MergePolicy
+	variables []deppy.Variable
+	// positions maps Identifiers to indexes of variables
+	positions map[deppy.Identifier]int
+	// merged holds the MergedVariables created by the receiver, by
+	// index, which unlike those it is given can be modified
+	merged map[int]*MergedVariable
+}
+
+func newVariableMerger(policy MergePolicy, size int) *variableMerger {
+	return &variableMerger{
+		policy:    policy,
+		variables: make([]deppy.Variable, 0, size),
+		positions: make(map[deppy.Identifier]int, size),
+		merged:    map[int]*MergedVariable{},
+	}
+}
+
+func (m *variableMerger) add(variable deppy.Variable) error {
+	i, ok := m.positions[variable.Identifier()]
+	if !ok {
+		m.positions[variable.Identifier()] = len(m.variables)
+		m.variables = append(m.variables, variable)
+		return nil
+	}
+	switch m.policy {
+	case UnionConstraints:
+		merged, ok := m.merged[i]
+		if !ok {
+			first := m.variables[i]
+			merged = &MergedVariable{
+				Variable:    first,
+				variables:   []deppy.Variable{first},
+				constraints: append([]deppy.Constraint(nil), first.Constraints()...),
+			}
+			// merge into the variables first merged, rather than
+			// nesting MergedVariables
+			if previous, ok := first.(*MergedVariable); ok {
+				merged.Variable = previous.Variable
+				merged.variables = append([]deppy.Variable(nil), previous.variables...)
+			}
+			m.merged[i] = merged
+			m.variables[i] = merged
+		}
+		merged.variables = append(merged.variables, variable)
+		merged.constraints = append(merged.constraints, variable.Constraints()...)
+	case LastWins:
+		m.variables[i] = variable
+	default:
+		return DuplicateVariable(variable.Identifier())
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package input_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+var _ = Describe("MergeVariables", func() {
+	var (
+		mandatory  deppy.Constraint
+		prohibited deppy.Constraint
+		dependency deppy.Constraint
+		variables  []deppy.Variable
+	)
+
+	BeforeEach(func() {
+		mandatory = constraint.Mandatory()
+		prohibited = constraint.Prohibited()
+		dependency = constraint.Dependency("c")
+		variables = []deppy.Variable{
+			input.NewSimpleVariable("a", mandatory),
+			input.NewSimpleVariable("b"),
+			input.NewSimpleVariable("a", prohibited),
+			input.NewSimpleVariable("c"),
+			input.NewSimpleVariable("a", dependency),
+		}
+	})
+
+	It("should return variables with distinct identifiers as they are", func() {
+		for _, policy := range []input.MergePolicy{input.FailOnDuplicate, input.UnionConstraints, input.LastWins} {
+			merged, err := input.MergeVariables(policy, variables[:2])
+			Expect(err).NotTo(HaveOccurred())
+			Expect(merged).To(Equal(variables[:2]))
+		}
+	})
+
+	It("should report duplicates with FailOnDuplicate", func() {
+		merged, err := input.MergeVariables(input.FailOnDuplicate, variables)
+		Expect(merged).To(BeNil())
+		Expect(err).To(Equal(input.DuplicateVariable("a")))
+		Expect(err).To(MatchError(`duplicate variable "a"`))
+	})
+
+	It("should combine the constraints of duplicates with UnionConstraints", func() {
+		merged, err := input.MergeVariables(input.UnionConstraints, variables)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(variableIDs(merged)).To(Equal([]deppy.Identifier{"a", "b", "c"}))
+		Expect(merged[0].Constraints()).To(Equal([]deppy.Constraint{mandatory, prohibited, dependency}))
+		Expect(merged[0].(*input.MergedVariable).Unwrap()).To(BeIdenticalTo(variables[0]))
+
+		// the merged variables are left untouched
+		Expect(variables[0].Constraints()).To(Equal([]deppy.Constraint{mandatory}))
+	})
+
+	It("should not modify merged variables when merging them again", func() {
+		merged, err := input.MergeVariables(input.UnionConstraints, variables[:3])
+		Expect(err).NotTo(HaveOccurred())
+		again, err := input.MergeVariables(input.UnionConstraints, append(merged, variables[4]))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(again[0].Constraints()).To(Equal([]deppy.Constraint{mandatory, prohibited, dependency}))
+		Expect(again[0].(*input.MergedVariable).Unwrap()).To(BeIdenticalTo(variables[0]))
+		Expect(merged[0].Constraints()).To(Equal([]deppy.Constraint{mandatory, prohibited}))
+	})
+
+	It("should keep the last duplicate with LastWins", func() {
+		merged, err := input.MergeVariables(input.LastWins, variables)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(merged).To(Equal([]deppy.Variable{variables[4], variables[1], variables[3]}))
+	})
+
+	It("should merge the variables of an AggregateVariableSource in the order of the sources", func() {
+		aggregate := input.NewAggregateVariableSource(
+			variableSourceFunc(func(context.Context, input.EntitySource) ([]deppy.Variable, error) {
+				return variables[:2], nil
+			}),
+			variableSourceFunc(func(context.Context, input.EntitySource) ([]deppy.Variable, error) {
+				return variables[2:], nil
+			}),
+		)
+		ctx := context.Background()
+		entitySource := input.NewCacheQuerier(map[deppy.Identifier]input.Entity{})
+
+		_, err := aggregate.GetVariables(ctx, entitySource)
+		Expect(err).To(MatchError(input.DuplicateVariable("a")))
+		Expect(err).To(MatchError(ContainSubstring("source 1")))
+
+		aggregate.Merge = input.UnionConstraints
+		merged, err := aggregate.GetVariables(ctx, entitySource)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(variableIDs(merged)).To(Equal([]deppy.Identifier{"a", "b", "c"}))
+		Expect(merged[0].Constraints()).To(Equal([]deppy.Constraint{mandatory, prohibited, dependency}))
+
+		aggregate.Merge = input.LastWins
+		merged, err = aggregate.GetVariables(ctx, entitySource)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(merged).To(Equal([]deppy.Variable{variables[4], variables[1], variables[3]}))
+	})
+})
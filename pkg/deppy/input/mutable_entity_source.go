@@ -0,0 +1,243 @@
+package input
+
+import (
+	"context"
+	"sync"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+)
+
+// Snapshotter is implemented by EntitySources whose entities change
+// over time. Snapshot returns an EntitySource holding the entities as
+// they are at the time of the call, unaffected by later changes.
+type Snapshotter interface {
+	Snapshot() EntitySource
+}
+
+// SnapshotOf returns a snapshot of source if it is a Snapshotter, and
+// source itself otherwise.
+func SnapshotOf(source EntitySource) EntitySource {
+	if snapshotter, ok := source.(Snapshotter); ok {
+		return snapshotter.Snapshot()
+	}
+	return source
+}
+
+var (
+	_ WatchableEntitySource = &MutableEntitySource{}
+	_ Snapshotter           = &MutableEntitySource{}
+)
+
+// MutableEntitySource is an in-memory EntitySource whose entities can
+// be added, updated and deleted, and whose changes can be watched. It
+// is safe for concurrent use.
+//
+// Reads are served from immutable snapshots, so that every query sees
+// the entities as they were when it started, in Identifier order, and
+// callbacks may modify the receiver. Use Snapshot to make several
+// queries against the same entities, as DeppySolver does for the
+// duration of a resolution. Entities are copied on the way in and out,
+// as are those of watch events, so neither readers nor writers share
+// property maps with the receiver or its snapshots.
+//
+// Writers never wait for queries to complete; a new snapshot is built
+// on the first read following a change. Building it sorts every
+// entity, so it costs O(n log n) for n entities however small the
+// change: batch changes with Update rather than reading between them.
+type MutableEntitySource struct {
+	// wmu serializes writers. The entities map is only modified by
+	// the holder of wmu, which can therefore read it without mu.
+	wmu sync.Mutex
+
+	mu       sync.RWMutex
+	entities map[deppy.Identifier]Entity
+	version  uint64
+	snapshot *IndexedEntitySource
+
+	watchers entityWatchers
+}
+
+// NewMutableEntitySource returns a MutableEntitySource holding a copy
+// of the given entities, including their property maps.
+func NewMutableEntitySource(entities map[deppy.Identifier]Entity) *MutableEntitySource {
+	s := &MutableEntitySource{
+		entities: make(map[deppy.Identifier]Entity, len(entities)),
+	}
+	for id, entity := range entities {
+		s.entities[id] = copyEntity(entity)
+	}
+	return s
+}
+
+// EntityTx stages changes to a MutableEntitySource, which are applied
+// atomically by MutableEntitySource.Update.
+type EntityTx struct {
+	source *MutableEntitySource
+	// staged holds the staged entities by Identifier, nil for
+	// deletions.
+	staged map[deppy.Identifier]*Entity
+	events []EntityEvent
+}
+
+// Get returns a copy of the entity with the given Identifier,
+// including the changes staged by the receiver. Modifying it doesn't
+// affect the source; stage the changes with Set instead.
+func (tx *EntityTx) Get(id deppy.Identifier) *Entity {
+	if entity, ok := tx.staged[id]; ok {
+		if entity == nil {
+			return nil
+		}
+		copied := copyEntity(*entity)
+		return &copied
+	}
+	if entity, ok := tx.source.entities[id]; ok {
+		copied := copyEntity(entity)
+		return &copied
+	}
+	return nil
+}
+
+// Set stages the addition of a copy of entity, replacing any entity
+// with the same Identifier. Later changes to entity don't affect the
+// source.
+func (tx *EntityTx) Set(entity Entity) {
+	entity = copyEntity(entity)
+	event := EntityEvent{Type: EntityAdded, Entity: copyEntity(entity)}
+	if tx.exists(entity.ID) {
+		event.Type = EntityUpdated
+	}
+	tx.staged[entity.ID] = &entity
+	tx.events = append(tx.events, event)
+}
+
+// exists returns true if there is an entity with the given Identifier,
+// including the changes staged by the receiver.
+func (tx *EntityTx) exists(id deppy.Identifier) bool {
+	if entity, ok := tx.staged[id]; ok {
+		return entity != nil
+	}
+	_, ok := tx.source.entities[id]
+	return ok
+}
+
+// Delete stages the deletion of the entity with the given Identifier,
+// and returns true if there is one.
+func (tx *EntityTx) Delete(id deppy.Identifier) bool {
+	entity := tx.Get(id)
+	if entity == nil {
+		return false
+	}
+	tx.staged[id] = nil
+	tx.events = append(tx.events, EntityEvent{Type: EntityDeleted, Entity: *entity})
+	return true
+}
+
+// Update calls fn with a transaction, and applies the changes it
+// stages if it returns nil. Either all changes are visible to readers
+// or none are, and watchers are only notified of applied changes.
+// Updates are serialized; fn must not modify the receiver other than
+// through the transaction.
+func (s *MutableEntitySource) Update(fn func(tx *EntityTx) error) error {
+	s.wmu.Lock()
+	defer s.wmu.Unlock()
+	tx := &EntityTx{source: s, staged: map[deppy.Identifier]*Entity{}}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if len(tx.events) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	for id, entity := range tx.staged {
+		if entity == nil {
+			delete(s.entities, id)
+		} else {
+			s.entities[id] = *entity
+		}
+	}
+	s.version++
+	s.snapshot = nil
+	s.mu.Unlock()
+	s.watchers.broadcast(tx.events...)
+	return nil
+}
+
+// Set adds entity to the receiver, replacing any entity with the same
+// Identifier.
+func (s *MutableEntitySource) Set(entity Entity) {
+	_ = s.Update(func(tx *EntityTx) error {
+		tx.Set(entity)
+		return nil
+	})
+}
+
+// Delete deletes the entity with the given Identifier, and returns
+// true if there was one.
+func (s *MutableEntitySource) Delete(id deppy.Identifier) bool {
+	var deleted bool
+	_ = s.Update(func(tx *EntityTx) error {
+		deleted = tx.Delete(id)
+		return nil
+	})
+	return deleted
+}
+
+// Len returns the number of entities held by the receiver.
+func (s *MutableEntitySource) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entities)
+}
+
+// Version returns the number of updates applied to the receiver.
+func (s *MutableEntitySource) Version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
+// Snapshot returns an immutable EntitySource holding the entities of
+// the receiver at the time of the call.
+func (s *MutableEntitySource) Snapshot() EntitySource {
+	return s.current()
+}
+
+// Watch returns a channel receiving the changes made to the receiver
+// after the call. Watchers that don't keep up don't slow down writers;
+// their events are queued instead.
+func (s *MutableEntitySource) Watch(ctx context.Context) <-chan EntityEvent {
+	return s.watchers.watch(ctx)
+}
+
+func (s *MutableEntitySource) Get(ctx context.Context, id deppy.Identifier) *Entity {
+	return s.current().Get(ctx, id)
+}
+
+func (s *MutableEntitySource) Filter(ctx context.Context, filter Predicate) (EntityList, error) {
+	return s.current().Filter(ctx, filter)
+}
+
+func (s *MutableEntitySource) GroupBy(ctx context.Context, fn GroupByFunction) (EntityListMap, error) {
+	return s.current().GroupBy(ctx, fn)
+}
+
+func (s *MutableEntitySource) Iterate(ctx context.Context, fn IteratorFunction) error {
+	return s.current().Iterate(ctx, fn)
+}
+
+// current returns the snapshot of the current entities, building it if
+// needed.
+func (s *MutableEntitySource) current() *IndexedEntitySource {
+	s.mu.RLock()
+	snapshot := s.snapshot
+	s.mu.RUnlock()
+	if snapshot != nil {
+		return snapshot
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.snapshot == nil {
+		s.snapshot = indexEntities(s.entities)
+	}
+	return s.snapshot
+}
@@ -0,0 +1,221 @@
+package input_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+var _ = Describe("MutableEntitySource", func() {
+	var (
+		ctx    context.Context
+		source *input.MutableEntitySource
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		source = input.NewMutableEntitySource(map[deppy.Identifier]input.Entity{
+			"a": *input.NewEntity("a", map[string]string{"version": "1"}),
+		})
+	})
+
+	It("should add, update and delete entities", func() {
+		source.Set(*input.NewEntity("b", map[string]string{"version": "1"}))
+		source.Set(*input.NewEntity("a", map[string]string{"version": "2"}))
+		Expect(source.Len()).To(Equal(2))
+		Expect(source.Get(ctx, "a").Properties).To(HaveKeyWithValue("version", "2"))
+
+		Expect(source.Delete("a")).To(BeTrue())
+		Expect(source.Delete("a")).To(BeFalse())
+		Expect(source.Get(ctx, "a")).To(BeNil())
+
+		el, err := source.Filter(ctx, func(*input.Entity) bool { return true })
+		Expect(err).NotTo(HaveOccurred())
+		Expect(el.CollectIds()).To(Equal([]deppy.Identifier{"b"}))
+	})
+
+	It("should allow callbacks to modify it", func() {
+		Expect(source.Iterate(ctx, func(entity *input.Entity) error {
+			source.Delete(entity.ID)
+			return nil
+		})).To(Succeed())
+		Expect(source.Len()).To(BeZero())
+	})
+
+	It("should notify watchers of changes in order", func() {
+		watchCtx, cancel := context.WithCancel(ctx)
+		events := source.Watch(watchCtx)
+
+		source.Set(*input.NewEntity("b", nil))
+		source.Set(*input.NewEntity("a", map[string]string{"version": "2"}))
+		source.Delete("b")
+		source.Delete("missing")
+
+		Expect(<-events).To(Equal(input.EntityEvent{Type: input.EntityAdded, Entity: *input.NewEntity("b", nil)}))
+		Expect(<-events).To(Equal(input.EntityEvent{Type: input.EntityUpdated, Entity: *input.NewEntity("a", map[string]string{"version": "2"})}))
+		Expect(<-events).To(Equal(input.EntityEvent{Type: input.EntityDeleted, Entity: *input.NewEntity("b", nil)}))
+		Consistently(events).ShouldNot(Receive())
+
+		cancel()
+		Eventually(events).Should(BeClosed())
+		source.Set(*input.NewEntity("c", nil))
+	})
+
+	It("should not be slowed down by watchers that don't receive events", func() {
+		_ = source.Watch(ctx)
+		for i := 0; i < 1000; i++ {
+			source.Set(*input.NewEntity("a", nil))
+		}
+	})
+
+	It("should apply the changes of a transaction atomically", func() {
+		events := source.Watch(ctx)
+		err := source.Update(func(tx *input.EntityTx) error {
+			tx.Set(*input.NewEntity("b", nil))
+			Expect(tx.Get("b")).NotTo(BeNil())
+			Expect(tx.Delete("a")).To(BeTrue())
+			Expect(tx.Get("a")).To(BeNil())
+			Expect(source.Get(ctx, "a")).NotTo(BeNil())
+			return nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(source.Get(ctx, "a")).To(BeNil())
+		Expect(source.Get(ctx, "b")).NotTo(BeNil())
+		Expect(source.Version()).To(Equal(uint64(1)))
+		Expect(<-events).To(HaveField("Type", input.EntityAdded))
+		Expect(<-events).To(HaveField("Type", input.EntityDeleted))
+	})
+
+	It("should discard the changes of a failed transaction", func() {
+		events := source.Watch(ctx)
+		failure := errors.New("failure")
+		err := source.Update(func(tx *input.EntityTx) error {
+			tx.Set(*input.NewEntity("b", nil))
+			tx.Delete("a")
+			return failure
+		})
+		Expect(err).To(BeIdenticalTo(failure))
+		Expect(source.Get(ctx, "a")).NotTo(BeNil())
+		Expect(source.Get(ctx, "b")).To(BeNil())
+		Expect(source.Version()).To(BeZero())
+		Consistently(events).ShouldNot(Receive())
+	})
+
+	It("should keep snapshots unaffected by later changes", func() {
+		snapshot := input.SnapshotOf(source)
+		source.Set(*input.NewEntity("b", nil))
+		source.Delete("a")
+
+		Expect(snapshot.Get(ctx, "a")).NotTo(BeNil())
+		Expect(snapshot.Get(ctx, "b")).To(BeNil())
+		Expect(input.SnapshotOf(source).Get(ctx, "b")).NotTo(BeNil())
+	})
+
+	It("should not share property maps with its callers", func() {
+		properties := map[string]string{"version": "1"}
+		source = input.NewMutableEntitySource(map[deppy.Identifier]input.Entity{
+			"a": *input.NewEntity("a", properties),
+		})
+		properties["version"] = "2"
+		snapshot := input.SnapshotOf(source)
+		Expect(snapshot.Get(ctx, "a").Properties).To(HaveKeyWithValue("version", "1"))
+
+		entity := input.NewEntityWithValues("b", map[string]input.Value{"version": input.IntValue(1)})
+		source.Set(*entity)
+		entity.SetValue("version", input.IntValue(2))
+		Expect(source.Get(ctx, "b")).To(HaveField("Values", HaveKeyWithValue("version", input.IntValue(1))))
+
+		Expect(source.Update(func(tx *input.EntityTx) error {
+			tx.Get("a").Properties["version"] = "3"
+			staged := tx.Get("b")
+			staged.SetValue("version", input.IntValue(3))
+			tx.Set(*staged)
+			staged.SetValue("version", input.IntValue(4))
+			Expect(tx.Get("b")).To(HaveField("Values", HaveKeyWithValue("version", input.IntValue(3))))
+			return nil
+		})).To(Succeed())
+		Expect(source.Get(ctx, "a").Properties).To(HaveKeyWithValue("version", "1"))
+		Expect(source.Get(ctx, "b")).To(HaveField("Values", HaveKeyWithValue("version", input.IntValue(3))))
+		Expect(snapshot.Get(ctx, "a").Properties).To(HaveKeyWithValue("version", "1"))
+		Expect(snapshot.Get(ctx, "b")).To(BeNil())
+	})
+
+	It("should not share property maps with readers, snapshots or watchers", func() {
+		watchCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		events := source.Watch(watchCtx)
+		snapshot := source.Snapshot()
+
+		source.Get(ctx, "a").Properties["version"] = "mutated"
+		Expect(source.Iterate(ctx, func(entity *input.Entity) error {
+			entity.Properties["version"] = "mutated"
+			return nil
+		})).To(Succeed())
+		el, err := source.Filter(ctx, func(*input.Entity) bool { return true })
+		Expect(err).NotTo(HaveOccurred())
+		el[0].Properties["version"] = "mutated"
+
+		source.Set(*input.NewEntity("a", map[string]string{"version": "2"}))
+		var event input.EntityEvent
+		Eventually(events).Should(Receive(&event))
+		event.Entity.Properties["version"] = "mutated"
+
+		Expect(snapshot.Get(ctx, "a").Properties).To(HaveKeyWithValue("version", "1"))
+		Expect(source.Get(ctx, "a").Properties).To(HaveKeyWithValue("version", "2"))
+	})
+
+	It("should leave entities untouched when a transaction modifying them fails", func() {
+		snapshot := input.SnapshotOf(source)
+		err := source.Update(func(tx *input.EntityTx) error {
+			entity := tx.Get("a")
+			entity.Properties["version"] = "2"
+			entity.SetValue("channel", input.StringValue("stable"))
+			tx.Set(*entity)
+			return errors.New("failure")
+		})
+		Expect(err).To(MatchError("failure"))
+		for _, entities := range []input.EntitySource{source, snapshot} {
+			entity := entities.Get(ctx, "a")
+			Expect(entity.Properties).To(Equal(map[string]string{"version": "1"}))
+			Expect(entity.Values).To(BeEmpty())
+		}
+	})
+
+	It("should give readers consistent views while writers update it", func() {
+		source.Set(*input.NewEntity("b", nil))
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer GinkgoRecover()
+			for i := 0; i < 200; i++ {
+				// a and b are always swapped together
+				Expect(source.Update(func(tx *input.EntityTx) error {
+					value := fmt.Sprint(i)
+					tx.Set(*input.NewEntity("a", map[string]string{"value": value}))
+					tx.Set(*input.NewEntity("b", map[string]string{"value": value}))
+					return nil
+				})).To(Succeed())
+			}
+		}()
+		for i := 0; i < 200; i++ {
+			snapshot := source.Snapshot()
+			a, b := snapshot.Get(ctx, "a"), snapshot.Get(ctx, "b")
+			Expect(a.Properties["value"]).To(Equal(b.Properties["value"]))
+		}
+		wg.Wait()
+	})
+
+	It("should name event types", func() {
+		Expect(input.EntityAdded.String()).To(Equal("added"))
+		Expect(input.EntityUpdated.String()).To(Equal("updated"))
+		Expect(input.EntityDeleted.String()).To(Equal("deleted"))
+	})
+})
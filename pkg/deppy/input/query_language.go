@@ -0,0 +1,723 @@
+package input
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/blang/semver/v4"
+
+	"github.com/operator-framework/deppy/internal/parse"
+)
+
+// SyntaxError is returned when a query cannot be parsed. Offset is the
+// byte offset of the error in the query, and Line and Column its
+// 1-based position.
+type SyntaxError = parse.SyntaxError
+
+// CompileQuery compiles a textual entity query into a Predicate, e.g.
+//
+//	olm.packageName == "etcd" && semver(olm.version) in ">=0.9 <1.0" && olm.channel in ["stable", "fast"]
+//
+// Bare names refer to entity properties and may contain letters,
+// digits and "_.-/"; property("name") refers to properties with any
+// other name. Literals are strings, integers, true, false and lists
+// such as ["a", "b"].
+//
+// Comparison operators are "==", "!=", "<", "<=", ">" and ">=". When
+// a string is compared with a value of another type, it is parsed as
+// that type first. "x in list" is true if x is equal to an element of
+// list, which may be a list property stored as a JSON string; "x in
+// s", where s is any other string, checks that the semantic version
+// x satisfies the range s if x is a semantic version, and that x is a
+// substring of s otherwise. Boolean expressions are combined with "!",
+// "&&" and "||", from tightest to loosest binding.
+//
+// The available functions are semver(x), int(x), bool(x) and
+// string(x), which convert their argument; len(x), the length of a
+// string, list or object; has(name), true if the entity has the named
+// property; field(x, "name"), a field of an object; property("name");
+// and id(), the identifier of the entity.
+//
+// Any comparison involving a missing property or a value that cannot be
+// converted is false.
+func CompileQuery(query string) (Predicate, error) {
+	p := &queryParser{lexer: queryLexer{parse.Scanner{Input: query}}}
+	p.next()
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.err != nil || p.token.kind != queryEOF {
+		return nil, p.errorf(p.token, "unexpected %s", p.token)
+	}
+	return node.eval, nil
+}
+
+// MustCompileQuery is like CompileQuery but panics if the query cannot
+// be compiled.
+func MustCompileQuery(query string) Predicate {
+	predicate, err := CompileQuery(query)
+	if err != nil {
+		panic(err)
+	}
+	return predicate
+}
+
+// queryNode is a boolean expression over an entity.
+type queryNode interface {
+	eval(entity *Entity) bool
+}
+
+// queryOperand is an expression whose value depends on an entity. The
+// second return value is false if the value is missing or invalid.
+type queryOperand interface {
+	value(entity *Entity) (Value, bool)
+}
+
+type andNode struct {
+	left, right queryNode
+}
+
+func (n andNode) eval(entity *Entity) bool {
+	return n.left.eval(entity) && n.right.eval(entity)
+}
+
+type orNode struct {
+	left, right queryNode
+}
+
+func (n orNode) eval(entity *Entity) bool {
+	return n.left.eval(entity) || n.right.eval(entity)
+}
+
+type notNode struct {
+	operand queryNode
+}
+
+func (n notNode) eval(entity *Entity) bool {
+	return !n.operand.eval(entity)
+}
+
+// truthNode is an operand used as a boolean expression.
+type truthNode struct {
+	operand queryOperand
+}
+
+func (n truthNode) eval(entity *Entity) bool {
+	v, ok := n.operand.value(entity)
+	if !ok {
+		return false
+	}
+	v, ok = convert(v, BoolKind)
+	b, _ := v.AsBool()
+	return ok && b
+}
+
+type comparisonNode struct {
+	operator    string
+	left, right queryOperand
+	// versionRange is set when the right operand is a constant
+	// semantic version range.
+	versionRange semver.Range
+}
+
+func (n comparisonNode) eval(entity *Entity) bool {
+	left, ok := n.left.value(entity)
+	if !ok {
+		return false
+	}
+	right, ok := n.right.value(entity)
+	if !ok {
+		return false
+	}
+	switch n.operator {
+	case "==":
+		return equal(left, right)
+	case "!=":
+		return !equal(left, right)
+	case "in":
+		return n.in(left, right)
+	}
+	c, ok := compare(left, right)
+	if !ok {
+		return false
+	}
+	switch n.operator {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	}
+	return c >= 0
+}
+
+func (n comparisonNode) in(left, right Value) bool {
+	if list, ok := convert(right, ListKind); ok {
+		list, _ := list.AsList()
+		for _, each := range list {
+			if equal(left, each) {
+				return true
+			}
+		}
+		return false
+	}
+	s, ok := right.AsString()
+	if !ok {
+		return false
+	}
+	if version, ok := left.AsSemver(); ok {
+		r := n.versionRange
+		if r == nil {
+			var err error
+			if r, err = parseVersionRange(s); err != nil {
+				return false
+			}
+		}
+		return r(version)
+	}
+	sub, ok := left.AsString()
+	return ok && strings.Contains(s, sub)
+}
+
+// parseVersionRange parses a semantic version range, accepting
+// versions without minor or patch numbers such as ">=0.9 <1".
+func parseVersionRange(s string) (semver.Range, error) {
+	fields := strings.Fields(s)
+	for i, field := range fields {
+		version := strings.TrimLeft(field, "<>=!")
+		if version == "" || strings.Trim(version, "0123456789.") != "" {
+			continue
+		}
+		for n := strings.Count(version, "."); n < 2; n++ {
+			version += ".0"
+		}
+		fields[i] = field[:len(field)-len(strings.TrimLeft(field, "<>=!"))] + version
+	}
+	return semver.ParseRange(strings.Join(fields, " "))
+}
+
+// convert returns v as a Value of the given kind, parsing it if it is
+// a string.
+func convert(v Value, kind ValueKind) (Value, bool) {
+	if v.Kind() == kind {
+		return v, true
+	}
+	s, ok := v.AsString()
+	if !ok {
+		return Value{}, false
+	}
+	v, err := ParseValue(kind, s)
+	return v, err == nil
+}
+
+// coerce returns a and b converted to a common kind, if possible.
+func coerce(a, b Value) (Value, Value, bool) {
+	if a.Kind() == b.Kind() {
+		return a, b, true
+	}
+	if a.Kind() == StringKind {
+		a, ok := convert(a, b.Kind())
+		return a, b, ok
+	}
+	b, ok := convert(b, a.Kind())
+	return a, b, ok
+}
+
+func equal(a, b Value) bool {
+	a, b, ok := coerce(a, b)
+	if !ok {
+		return false
+	}
+	switch a.Kind() {
+	case SemverKind:
+		av, _ := a.AsSemver()
+		bv, _ := b.AsSemver()
+		return av.EQ(bv)
+	case ListKind, ObjectKind:
+		return reflect.DeepEqual(a, b)
+	}
+	c, ok := compare(a, b)
+	if ok {
+		return c == 0
+	}
+	ab, _ := a.AsBool()
+	bb, _ := b.AsBool()
+	return a.Kind() == BoolKind && ab == bb
+}
+
+func compare(a, b Value) (int, bool) {
+	a, b, ok := coerce(a, b)
+	if !ok {
+		return 0, false
+	}
+	switch a.Kind() {
+	case StringKind:
+		as, _ := a.AsString()
+		bs, _ := b.AsString()
+		return strings.Compare(as, bs), true
+	case IntKind:
+		ai, _ := a.AsInt()
+		bi, _ := b.AsInt()
+		switch {
+		case ai < bi:
+			return -1, true
+		case ai > bi:
+			return 1, true
+		}
+		return 0, true
+	case SemverKind:
+		av, _ := a.AsSemver()
+		bv, _ := b.AsSemver()
+		return av.Compare(bv), true
+	}
+	return 0, false
+}
+
+type propertyOperand string
+
+func (o propertyOperand) value(entity *Entity) (Value, bool) {
+	return entity.Value(string(o))
+}
+
+type literalOperand struct {
+	v Value
+}
+
+func (o literalOperand) value(*Entity) (Value, bool) {
+	return o.v, true
+}
+
+type listOperand []queryOperand
+
+func (o listOperand) value(entity *Entity) (Value, bool) {
+	values := make([]Value, 0, len(o))
+	for _, each := range o {
+		v, ok := each.value(entity)
+		if !ok {
+			// missing elements can't be equal to anything
+			continue
+		}
+		values = append(values, v)
+	}
+	return ListValue(values...), true
+}
+
+type callOperand struct {
+	name string
+	fn   func(entity *Entity, args []queryOperand) (Value, bool)
+	args []queryOperand
+}
+
+func (o callOperand) value(entity *Entity) (Value, bool) {
+	return o.fn(entity, o.args)
+}
+
+type queryFunction struct {
+	arity int
+	// literal requires every argument to be a string literal or a
+	// bare property name, which is then passed as a string.
+	literal bool
+	fn      func(entity *Entity, args []queryOperand) (Value, bool)
+}
+
+func conversion(kind ValueKind) queryFunction {
+	return queryFunction{arity: 1, fn: func(entity *Entity, args []queryOperand) (Value, bool) {
+		v, ok := args[0].value(entity)
+		if !ok {
+			return Value{}, false
+		}
+		if kind == StringKind {
+			return StringValue(v.String()), true
+		}
+		return convert(v, kind)
+	}}
+}
+
+var queryFunctions = map[string]queryFunction{
+	"semver": conversion(SemverKind),
+	"int":    conversion(IntKind),
+	"bool":   conversion(BoolKind),
+	"string": conversion(StringKind),
+	"len": {arity: 1, fn: func(entity *Entity, args []queryOperand) (Value, bool) {
+		v, ok := args[0].value(entity)
+		if !ok {
+			return Value{}, false
+		}
+		switch v.Kind() {
+		case StringKind:
+			s, _ := v.AsString()
+			return IntValue(int64(utf8.RuneCountInString(s))), true
+		case ListKind:
+			list, _ := v.AsList()
+			return IntValue(int64(len(list))), true
+		case ObjectKind:
+			object, _ := v.AsObject()
+			return IntValue(int64(len(object))), true
+		}
+		return Value{}, false
+	}},
+	"has": {arity: 1, literal: true, fn: func(entity *Entity, args []queryOperand) (Value, bool) {
+		name, _ := args[0].value(entity)
+		_, ok := entity.Value(name.String())
+		return BoolValue(ok), true
+	}},
+	"property": {arity: 1, literal: true, fn: func(entity *Entity, args []queryOperand) (Value, bool) {
+		name, _ := args[0].value(entity)
+		return entity.Value(name.String())
+	}},
+	"field": {arity: 2, fn: func(entity *Entity, args []queryOperand) (Value, bool) {
+		v, ok := args[0].value(entity)
+		if !ok {
+			return Value{}, false
+		}
+		v, ok = convert(v, ObjectKind)
+		if !ok {
+			return Value{}, false
+		}
+		name, ok := args[1].value(entity)
+		if !ok {
+			return Value{}, false
+		}
+		return v.Get(name.String())
+	}},
+	"id": {arity: 0, fn: func(entity *Entity, _ []queryOperand) (Value, bool) {
+		return StringValue(string(entity.Identifier())), true
+	}},
+}
+
+type queryTokenKind int
+
+const (
+	queryEOF queryTokenKind = iota
+	queryName
+	queryString
+	queryInt
+	queryOperator
+	queryLeftParen
+	queryRightParen
+	queryLeftBracket
+	queryRightBracket
+	queryComma
+	queryAnd
+	queryOr
+	queryNot
+)
+
+type queryToken struct {
+	kind   queryTokenKind
+	text   string
+	offset int
+}
+
+func (t queryToken) String() string {
+	switch t.kind {
+	case queryEOF:
+		return "end of query"
+	case queryString:
+		return fmt.Sprintf("string %s", t.text)
+	case queryInt:
+		return fmt.Sprintf("integer %s", t.text)
+	case queryName:
+		return fmt.Sprintf("name %q", t.text)
+	}
+	return fmt.Sprintf("%q", t.text)
+}
+
+type queryLexer struct {
+	parse.Scanner
+}
+
+func isQueryNameRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("_.-/", r)
+}
+
+func (l *queryLexer) next() (queryToken, error) {
+	if !l.SkipSpace() {
+		return queryToken{kind: queryEOF, offset: l.Offset}, nil
+	}
+	start := l.Offset
+	rest := l.Rest()
+	for _, op := range []struct {
+		text string
+		kind queryTokenKind
+	}{
+		{"&&", queryAnd},
+		{"||", queryOr},
+		{"==", queryOperator},
+		{"!=", queryOperator},
+		{"<=", queryOperator},
+		{">=", queryOperator},
+		{"<", queryOperator},
+		{">", queryOperator},
+		{"!", queryNot},
+		{"(", queryLeftParen},
+		{")", queryRightParen},
+		{"[", queryLeftBracket},
+		{"]", queryRightBracket},
+		{",", queryComma},
+	} {
+		if strings.HasPrefix(rest, op.text) {
+			l.Offset += len(op.text)
+			return queryToken{kind: op.kind, text: op.text, offset: start}, nil
+		}
+	}
+
+	if rest[0] == '"' {
+		quoted, err := l.Quoted()
+		if err != nil {
+			return queryToken{}, err
+		}
+		return queryToken{kind: queryString, text: quoted, offset: start}, nil
+	}
+
+	r, _ := utf8.DecodeRuneInString(rest)
+	if unicode.IsDigit(r) || r == '-' {
+		l.Offset++
+		for l.Offset < len(l.Input) && l.Input[l.Offset] >= '0' && l.Input[l.Offset] <= '9' {
+			l.Offset++
+		}
+		text := l.Input[start:l.Offset]
+		if text == "-" {
+			return queryToken{}, l.Errorf(start, `expected digits after "-"`)
+		}
+		return queryToken{kind: queryInt, text: text, offset: start}, nil
+	}
+
+	if unicode.IsLetter(r) || r == '_' {
+		for l.Offset < len(l.Input) {
+			r, size := utf8.DecodeRuneInString(l.Input[l.Offset:])
+			if !isQueryNameRune(r) {
+				break
+			}
+			l.Offset += size
+		}
+		text := l.Input[start:l.Offset]
+		if text == "in" {
+			return queryToken{kind: queryOperator, text: text, offset: start}, nil
+		}
+		return queryToken{kind: queryName, text: text, offset: start}, nil
+	}
+	return queryToken{}, l.Errorf(start, "unexpected character %q", r)
+}
+
+type queryParser struct {
+	lexer queryLexer
+	token queryToken
+	err   error
+}
+
+func (p *queryParser) next() {
+	if p.err != nil {
+		return
+	}
+	p.token, p.err = p.lexer.next()
+	if p.err != nil {
+		p.token = queryToken{kind: queryEOF, offset: p.err.(*SyntaxError).Offset}
+	}
+}
+
+func (p *queryParser) errorf(at queryToken, format string, args ...interface{}) error {
+	if p.err != nil {
+		return p.err
+	}
+	return p.lexer.Errorf(at.offset, format, args...)
+}
+
+func (p *queryParser) expect(kind queryTokenKind, what string) (queryToken, error) {
+	t := p.token
+	if t.kind != kind || p.err != nil {
+		return t, p.errorf(t, "expected %s, found %s", what, t)
+	}
+	p.next()
+	return t, nil
+}
+
+func (p *queryParser) parseOr() (queryNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.token.kind == queryOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseAnd() (queryNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.token.kind == queryAnd {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *queryParser) parseUnary() (queryNode, error) {
+	switch p.token.kind {
+	case queryNot:
+		p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	case queryLeftParen:
+		p.next()
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if _, err := p.expect(queryRightParen, `")"`); err != nil {
+			return nil, err
+		}
+		return node, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *queryParser) parseComparison() (queryNode, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.token.kind != queryOperator || p.err != nil {
+		return truthNode{operand: left}, nil
+	}
+	operator := p.token
+	p.next()
+	rightToken := p.token
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	node := comparisonNode{operator: operator.text, left: left, right: right}
+	if literal, ok := right.(literalOperand); ok && operator.text == "in" && isSemverCall(left) {
+		s, ok := literal.v.AsString()
+		if !ok {
+			return nil, p.errorf(rightToken, "expected semver range or list, found %s", rightToken)
+		}
+		if node.versionRange, err = parseVersionRange(s); err != nil {
+			return nil, p.errorf(rightToken, "invalid semver range %s: %v", rightToken.text, err)
+		}
+	}
+	return node, nil
+}
+
+func isSemverCall(o queryOperand) bool {
+	call, ok := o.(callOperand)
+	return ok && call.name == "semver"
+}
+
+func (p *queryParser) parseOperand() (queryOperand, error) {
+	t := p.token
+	if p.err != nil {
+		return nil, p.errorf(t, "")
+	}
+	switch t.kind {
+	case queryString:
+		p.next()
+		s, _ := strconv.Unquote(t.text)
+		return literalOperand{v: StringValue(s)}, nil
+	case queryInt:
+		p.next()
+		i, err := strconv.ParseInt(t.text, 10, 64)
+		if err != nil {
+			return nil, p.errorf(t, "integer %s out of range", t.text)
+		}
+		return literalOperand{v: IntValue(i)}, nil
+	case queryLeftBracket:
+		return p.parseList()
+	case queryName:
+		p.next()
+		if p.token.kind == queryLeftParen {
+			return p.parseCall(t)
+		}
+		switch t.text {
+		case "true":
+			return literalOperand{v: BoolValue(true)}, nil
+		case "false":
+			return literalOperand{v: BoolValue(false)}, nil
+		}
+		return propertyOperand(t.text), nil
+	}
+	return nil, p.errorf(t, "expected property, literal, function call, \"!\" or \"(\", found %s", t)
+}
+
+func (p *queryParser) parseList() (queryOperand, error) {
+	p.next() // consume "["
+	var list listOperand
+	for p.token.kind != queryRightBracket || p.err != nil {
+		if len(list) > 0 {
+			if _, err := p.expect(queryComma, `"," or "]"`); err != nil {
+				return nil, err
+			}
+		}
+		element, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, element)
+	}
+	p.next()
+	return list, nil
+}
+
+func (p *queryParser) parseCall(name queryToken) (queryOperand, error) {
+	fn, ok := queryFunctions[name.text]
+	if !ok {
+		return nil, p.errorf(name, "unknown function %q", name.text)
+	}
+	p.next() // consume "("
+
+	var args []queryOperand
+	for p.token.kind != queryRightParen || p.err != nil {
+		if len(args) > 0 {
+			if _, err := p.expect(queryComma, `"," or ")"`); err != nil {
+				return nil, err
+			}
+		}
+		t := p.token
+		arg, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if fn.literal {
+			switch arg := arg.(type) {
+			case propertyOperand:
+				args = append(args, literalOperand{v: StringValue(string(arg))})
+				continue
+			case literalOperand:
+				if arg.v.Kind() == StringKind {
+					args = append(args, arg)
+					continue
+				}
+			}
+			return nil, p.errorf(t, "%s expects a property name, found %s", name.text, t)
+		}
+		args = append(args, arg)
+	}
+	closing := p.token
+	p.next()
+	if len(args) != fn.arity {
+		return nil, p.errorf(closing, "%s expects %d argument(s), found %d", name.text, fn.arity, len(args))
+	}
+	return callOperand{name: name.text, fn: fn.fn, args: args}, nil
+}
@@ -0,0 +1,83 @@
+package input_test
+
+import (
+	"github.com/blang/semver/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+var _ = Describe("Query language", func() {
+	etcd := input.NewEntity("etcd-0.9.4", map[string]string{
+		"olm.packageName": "etcd",
+		"olm.version":     "0.9.4",
+		"olm.channel":     "stable",
+		"olm.gvk":         `[{"group":"etcd.database.coreos.com","version":"v1beta2","kind":"EtcdCluster"}]`,
+		"replicas":        "3",
+		"deprecated":      "false",
+		"with space":      "yes",
+		"channels":        `["stable","fast"]`,
+	})
+	typed := input.NewEntityWithValues("typed", map[string]input.Value{
+		"version":  input.SemverValue(semver.MustParse("1.2.3")),
+		"replicas": input.IntValue(5),
+		"tags":     input.ListValue(input.StringValue("a"), input.StringValue("b")),
+		"enabled":  input.BoolValue(true),
+		"owner":    input.ObjectValue(map[string]input.Value{"name": input.StringValue("alice")}),
+	})
+
+	DescribeTable("should evaluate queries",
+		func(query string, entity *input.Entity, expected bool) {
+			predicate, err := input.CompileQuery(query)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(predicate(entity)).To(Equal(expected))
+		},
+		Entry("the example query", `olm.packageName == "etcd" && semver(olm.version) in ">=0.9 <1.0" && olm.channel in ["stable","fast"]`, etcd, true),
+		Entry("a version outside the range", `semver(olm.version) in ">=1.0"`, etcd, false),
+		Entry("inequality", `olm.channel != "fast"`, etcd, true),
+		Entry("integer comparison of a string property", `replicas >= 3 && replicas < 4`, etcd, true),
+		Entry("integer comparison of a typed property", `replicas > 4`, typed, true),
+		Entry("semver comparison", `version > "1.2.0"`, typed, true),
+		Entry("semver range on a typed property", `version in "1.x"`, typed, true),
+		Entry("membership in a typed list", `"b" in tags`, typed, true),
+		Entry("membership in a JSON list", `"fast" in channels`, etcd, true),
+		Entry("fields of non-objects never compare", `field(property("olm.gvk"), "kind") == "EtcdCluster"`, etcd, false),
+		Entry("list length", `len(tags) == 2`, typed, true),
+		Entry("boolean properties", `enabled && !deprecated`, typed, true),
+		Entry("string booleans", `!deprecated`, etcd, true),
+		Entry("comparison with boolean literals", `deprecated == false`, etcd, true),
+		Entry("object fields", `field(owner, "name") == "alice"`, typed, true),
+		Entry("property existence", `has(olm.channel) && !has(olm.replaces)`, etcd, true),
+		Entry("properties with arbitrary names", `property("with space") == "yes"`, etcd, true),
+		Entry("entity identifier", `id() == "typed"`, typed, true),
+		Entry("substring", `olm.packageName in "etcd-operator"`, etcd, true),
+		Entry("missing properties never compare", `missing == "x" || missing != "x"`, etcd, false),
+		Entry("invalid conversions never compare", `int(olm.channel) == 0`, etcd, false),
+		Entry("operator precedence", `olm.channel == "fast" && replicas == 0 || olm.packageName == "etcd"`, etcd, true),
+		Entry("parentheses", `olm.channel == "fast" && (replicas == 0 || olm.packageName == "etcd")`, etcd, false),
+		Entry("negative integers", `replicas > -1`, typed, true),
+	)
+
+	DescribeTable("should report syntax errors",
+		func(query string, expected string) {
+			_, err := input.CompileQuery(query)
+			Expect(err).To(MatchError(expected))
+			Expect(err).To(BeAssignableToTypeOf(&input.SyntaxError{}))
+		},
+		Entry("empty query", ``, `syntax error at line 1, column 1: expected property, literal, function call, "!" or "(", found end of query`),
+		Entry("missing operand", `a ==`, `syntax error at line 1, column 5: expected property, literal, function call, "!" or "(", found end of query`),
+		Entry("unknown function", `foo(a) == 1`, `syntax error at line 1, column 1: unknown function "foo"`),
+		Entry("wrong arity", `semver(a, b) in "1.x"`, `syntax error at line 1, column 12: semver expects 1 argument(s), found 2`),
+		Entry("invalid range", `semver(v) in "not a range"`, `syntax error at line 1, column 14: invalid semver range "not a range": Could not get version from string: "not"`),
+		Entry("unterminated string", `a == "b`, `syntax error at line 1, column 6: unterminated or malformed string`),
+		Entry("unterminated list", `a in ["b"`, `syntax error at line 1, column 10: expected "," or "]", found end of query`),
+		Entry("trailing tokens", `a == "b" c`, `syntax error at line 1, column 10: unexpected name "c"`),
+		Entry("unexpected character", "a == \"b\" &&\n  c ~ d", `syntax error at line 2, column 5: unexpected character '~'`),
+		Entry("non literal name", `has(int(a))`, `syntax error at line 1, column 5: has expects a property name, found name "int"`),
+	)
+
+	It("should panic on invalid queries when told to", func() {
+		Expect(func() { input.MustCompileQuery(`(`) }).To(Panic())
+	})
+})
@@ -0,0 +1,268 @@
+package input
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/blang/semver/v4"
+)
+
+// ValueKind is the type of a Value.
+type ValueKind int
+
+const (
+	InvalidKind ValueKind = iota
+	StringKind
+	IntKind
+	BoolKind
+	SemverKind
+	ListKind
+	ObjectKind
+)
+
+func (k ValueKind) String() string {
+	switch k {
+	case StringKind:
+		return "string"
+	case IntKind:
+		return "int"
+	case BoolKind:
+		return "bool"
+	case SemverKind:
+		return "semver"
+	case ListKind:
+		return "list"
+	case ObjectKind:
+		return "object"
+	}
+	return "invalid"
+}
+
+// Value is a typed entity property value. The zero Value is invalid.
+//
+// Values are encoded to JSON as the corresponding JSON type. Semantic
+// versions are encoded as strings, and are decoded as such; use
+// Entity.SemverProperty to read them regardless of their kind.
+type Value struct {
+	kind   ValueKind
+	s      string
+	i      int64
+	b      bool
+	v      semver.Version
+	list   []Value
+	object map[string]Value
+}
+
+// StringValue returns a Value holding s.
+func StringValue(s string) Value {
+	return Value{kind: StringKind, s: s}
+}
+
+// IntValue returns a Value holding i.
+func IntValue(i int64) Value {
+	return Value{kind: IntKind, i: i}
+}
+
+// BoolValue returns a Value holding b.
+func BoolValue(b bool) Value {
+	return Value{kind: BoolKind, b: b}
+}
+
+// SemverValue returns a Value holding v.
+func SemverValue(v semver.Version) Value {
+	return Value{kind: SemverKind, v: v}
+}
+
+// ListValue returns a Value holding the given Values.
+func ListValue(values ...Value) Value {
+	if values == nil {
+		values = []Value{}
+	}
+	return Value{kind: ListKind, list: values}
+}
+
+// ObjectValue returns a Value holding the given named Values.
+func ObjectValue(fields map[string]Value) Value {
+	if fields == nil {
+		fields = map[string]Value{}
+	}
+	return Value{kind: ObjectKind, object: fields}
+}
+
+// Kind returns the type of the receiver.
+func (v Value) Kind() ValueKind {
+	return v.kind
+}
+
+// AsString returns the string held by the receiver, and whether it
+// holds one.
+func (v Value) AsString() (string, bool) {
+	return v.s, v.kind == StringKind
+}
+
+// AsInt returns the integer held by the receiver, and whether it holds
+// one.
+func (v Value) AsInt() (int64, bool) {
+	return v.i, v.kind == IntKind
+}
+
+// AsBool returns the boolean held by the receiver, and whether it
+// holds one.
+func (v Value) AsBool() (bool, bool) {
+	return v.b, v.kind == BoolKind
+}
+
+// AsSemver returns the semantic version held by the receiver, and
+// whether it holds one.
+func (v Value) AsSemver() (semver.Version, bool) {
+	return v.v, v.kind == SemverKind
+}
+
+// AsList returns the Values held by the receiver, and whether it is a
+// list.
+func (v Value) AsList() ([]Value, bool) {
+	return v.list, v.kind == ListKind
+}
+
+// AsObject returns the named Values held by the receiver, and whether
+// it is an object.
+func (v Value) AsObject() (map[string]Value, bool) {
+	return v.object, v.kind == ObjectKind
+}
+
+// Get returns the field of an object Value with the given name.
+func (v Value) Get(name string) (Value, bool) {
+	field, ok := v.object[name]
+	return field, ok
+}
+
+// String returns the string form of the receiver, as stored in
+// Entity.Properties: strings as they are, integers, booleans and
+// semantic versions in their usual textual form, and lists and objects
+// as JSON.
+func (v Value) String() string {
+	switch v.kind {
+	case StringKind:
+		return v.s
+	case IntKind:
+		return strconv.FormatInt(v.i, 10)
+	case BoolKind:
+		return strconv.FormatBool(v.b)
+	case SemverKind:
+		return v.v.String()
+	case ListKind, ObjectKind:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+	return ""
+}
+
+func (v Value) MarshalJSON() ([]byte, error) {
+	switch v.kind {
+	case StringKind:
+		return json.Marshal(v.s)
+	case IntKind:
+		return json.Marshal(v.i)
+	case BoolKind:
+		return json.Marshal(v.b)
+	case SemverKind:
+		return json.Marshal(v.v.String())
+	case ListKind:
+		return json.Marshal(v.list)
+	case ObjectKind:
+		return json.Marshal(v.object)
+	}
+	return nil, fmt.Errorf("cannot encode invalid value")
+}
+
+// UnmarshalJSON decodes strings, booleans, arrays and objects into
+// Values of the matching kind, and integers into IntKind Values. Other
+// numbers, such as fractions or integers that don't fit in 64 bits, are
+// kept as StringKind Values holding their JSON text.
+func (v *Value) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return fmt.Errorf("empty value")
+	}
+	switch data[0] {
+	case '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*v = StringValue(s)
+	case 't', 'f':
+		var b bool
+		if err := json.Unmarshal(data, &b); err != nil {
+			return err
+		}
+		*v = BoolValue(b)
+	case '[':
+		var list []Value
+		if err := json.Unmarshal(data, &list); err != nil {
+			return err
+		}
+		*v = ListValue(list...)
+	case '{':
+		var object map[string]Value
+		if err := json.Unmarshal(data, &object); err != nil {
+			return err
+		}
+		*v = ObjectValue(object)
+	case 'n':
+		return fmt.Errorf("null is not a valid value")
+	default:
+		var number json.Number
+		if err := json.Unmarshal(data, &number); err != nil {
+			return err
+		}
+		if i, err := number.Int64(); err == nil {
+			*v = IntValue(i)
+		} else {
+			*v = StringValue(number.String())
+		}
+	}
+	return nil
+}
+
+// ParseValue parses the string form of a Value of the given kind, as
+// returned by Value.String.
+func ParseValue(kind ValueKind, s string) (Value, error) {
+	switch kind {
+	case StringKind:
+		return StringValue(s), nil
+	case IntKind:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return Value{}, err
+		}
+		return IntValue(i), nil
+	case BoolKind:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return Value{}, err
+		}
+		return BoolValue(b), nil
+	case SemverKind:
+		v, err := semver.Parse(s)
+		if err != nil {
+			return Value{}, err
+		}
+		return SemverValue(v), nil
+	case ListKind, ObjectKind:
+		var v Value
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			return Value{}, err
+		}
+		if v.kind != kind {
+			return Value{}, fmt.Errorf("expected %s, found %s", kind, v.kind)
+		}
+		return v, nil
+	}
+	return Value{}, fmt.Errorf("cannot parse %s value", kind)
+}
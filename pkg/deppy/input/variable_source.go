@@ -36,3 +36,49 @@ func NewSimpleVariable(id deppy.Identifier, constraints ...deppy.Constraint) *Si
 		constraints: constraints,
 	}
 }
+
+var _ deppy.Variable = &EntityVariable{}
+
+// EntityVariable is a Variable standing for an Entity, with the same
+// Identifier, so that the entities selected by the solver can be read
+// from the solution without looking them up again.
+type EntityVariable struct {
+	*SimpleVariable
+	entity *Entity
+}
+
+// Entity returns the entity the variable stands for.
+func (e *EntityVariable) Entity() *Entity {
+	return e.entity
+}
+
+func NewEntityVariable(entity *Entity, constraints ...deppy.Constraint) *EntityVariable {
+	return &EntityVariable{
+		SimpleVariable: NewSimpleVariable(entity.ID, constraints...),
+		entity:         entity,
+	}
+}
+
+// EntityOf returns the entity variable stands for, if it has an
+// Entity method like EntityVariable, or is a MergedVariable one of
+// whose variables has one, the first such if several do.
+func EntityOf(variable deppy.Variable) (*Entity, bool) {
+	for {
+		switch v := variable.(type) {
+		case interface{ Entity() *Entity }:
+			entity := v.Entity()
+			return entity, entity != nil
+		case interface{ Variables() []deppy.Variable }:
+			for _, each := range v.Variables() {
+				if entity, ok := EntityOf(each); ok {
+					return entity, true
+				}
+			}
+			return nil, false
+		case interface{ Unwrap() deppy.Variable }:
+			variable = v.Unwrap()
+		default:
+			return nil, false
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package input_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+var _ = Describe("EntityVariable", func() {
+	var entity *input.Entity
+
+	BeforeEach(func() {
+		entity = input.NewEntity("a", map[string]string{"version": "1"})
+	})
+
+	It("should stand for its entity", func() {
+		mandatory := constraint.Mandatory()
+		variable := input.NewEntityVariable(entity, mandatory)
+		Expect(variable.Identifier()).To(Equal(deppy.Identifier("a")))
+		Expect(variable.Constraints()).To(Equal([]deppy.Constraint{mandatory}))
+		Expect(variable.Entity()).To(BeIdenticalTo(entity))
+
+		variable.AddConstraint(constraint.Prohibited())
+		Expect(variable.Constraints()).To(HaveLen(2))
+	})
+
+	It("should be found by EntityOf", func() {
+		var variable deppy.Variable = input.NewEntityVariable(entity)
+		found, ok := input.EntityOf(variable)
+		Expect(ok).To(BeTrue())
+		Expect(found).To(BeIdenticalTo(entity))
+
+		merged, err := input.MergeVariables(input.UnionConstraints, []deppy.Variable{
+			variable,
+			input.NewSimpleVariable("a", constraint.Prohibited()),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		found, ok = input.EntityOf(merged[0])
+		Expect(ok).To(BeTrue())
+		Expect(found).To(BeIdenticalTo(entity))
+	})
+
+	It("should be found by EntityOf after other merged variables", func() {
+		merged, err := input.MergeVariables(input.UnionConstraints, []deppy.Variable{
+			input.NewSimpleVariable("a", constraint.Mandatory()),
+			input.NewSimpleVariable("a", constraint.Prohibited()),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		_, ok := input.EntityOf(merged[0])
+		Expect(ok).To(BeFalse())
+
+		merged, err = input.MergeVariables(input.UnionConstraints, append(merged, input.NewEntityVariable(entity)))
+		Expect(err).NotTo(HaveOccurred())
+		found, ok := input.EntityOf(merged[0])
+		Expect(ok).To(BeTrue())
+		Expect(found).To(BeIdenticalTo(entity))
+		Expect(merged[0].(*input.MergedVariable).Variables()).To(HaveLen(3))
+	})
+
+	It("should not be found by EntityOf for other variables", func() {
+		found, ok := input.EntityOf(input.NewSimpleVariable("a"))
+		Expect(ok).To(BeFalse())
+		Expect(found).To(BeNil())
+	})
+})
@@ -0,0 +1,117 @@
+package input
+
+import (
+	"context"
+	"sync"
+)
+
+// EntityEventType is the kind of change an EntityEvent reports.
+type EntityEventType int
+
+const (
+	EntityAdded EntityEventType = iota
+	EntityUpdated
+	EntityDeleted
+)
+
+func (t EntityEventType) String() string {
+	switch t {
+	case EntityAdded:
+		return "added"
+	case EntityUpdated:
+		return "updated"
+	case EntityDeleted:
+		return "deleted"
+	}
+	return "unknown"
+}
+
+// EntityEvent reports a change to an entity of a
+// WatchableEntitySource. The Entity of a deletion is the entity as it
+// was before being deleted.
+type EntityEvent struct {
+	Type   EntityEventType
+	Entity Entity
+}
+
+// WatchableEntitySource is an EntitySource that notifies watchers of
+// changes to its entities.
+type WatchableEntitySource interface {
+	EntitySource
+	// Watch returns a channel receiving an EntityEvent for every
+	// change made after the call, in the order they are made. The
+	// channel is closed once ctx is done.
+	Watch(ctx context.Context) <-chan EntityEvent
+}
+
+// entityWatchers broadcasts EntityEvents to watchers without ever
+// blocking the broadcaster: each watcher has its own unbounded queue,
+// drained by a goroutine into its channel.
+type entityWatchers struct {
+	mu       sync.Mutex
+	watchers map[*entityWatcher]struct{}
+}
+
+type entityWatcher struct {
+	mu     sync.Mutex
+	queue  []EntityEvent
+	notify chan struct{}
+}
+
+// watch registers a new watcher until ctx is done.
+func (w *entityWatchers) watch(ctx context.Context) <-chan EntityEvent {
+	watcher := &entityWatcher{notify: make(chan struct{}, 1)}
+	w.mu.Lock()
+	if w.watchers == nil {
+		w.watchers = map[*entityWatcher]struct{}{}
+	}
+	w.watchers[watcher] = struct{}{}
+	w.mu.Unlock()
+
+	events := make(chan EntityEvent)
+	go func() {
+		defer close(events)
+		defer func() {
+			w.mu.Lock()
+			delete(w.watchers, watcher)
+			w.mu.Unlock()
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-watcher.notify:
+			}
+			watcher.mu.Lock()
+			queue := watcher.queue
+			watcher.queue = nil
+			watcher.mu.Unlock()
+			for _, event := range queue {
+				select {
+				case <-ctx.Done():
+					return
+				case events <- event:
+				}
+			}
+		}
+	}()
+	return events
+}
+
+// broadcast queues events for every watcher.
+func (w *entityWatchers) broadcast(events ...EntityEvent) {
+	if len(events) == 0 {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for watcher := range w.watchers {
+		watcher.mu.Lock()
+		watcher.queue = append(watcher.queue, events...)
+		watcher.mu.Unlock()
+		select {
+		case watcher.notify <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -16,14 +16,19 @@ import (
 // A successful execution of the solver can still end in an error when no solution can
 // be found.
 type Solution struct {
-	err       deppy.NotSatisfiable
-	selection map[deppy.Identifier]deppy.Variable
-	variables []deppy.Variable
+	err        deppy.NotSatisfiable
+	selection  map[deppy.Identifier]deppy.Variable
+	variables  []deppy.Variable
+	considered []deppy.Variable
 }
 
 // Error returns the resolution error in case the problem is unsat
 // on successful resolution, it will return nil
 func (s *Solution) Error() error {
+	if len(s.err) == 0 {
+		// an empty NotSatisfiable would make a non-nil error
+		return nil
+	}
 	return s.err
 }
 
@@ -40,6 +45,18 @@ func (s *Solution) IsSelected(identifier deppy.Identifier) bool {
 	return ok
 }
 
+// SelectedEntities returns the entities of the selected variables that
+// stand for one, such as input.EntityVariables, by Identifier.
+func (s *Solution) SelectedEntities() map[deppy.Identifier]*input.Entity {
+	entities := map[deppy.Identifier]*input.Entity{}
+	for id, variable := range s.selection {
+		if entity, ok := input.EntityOf(variable); ok {
+			entities[id] = entity
+		}
+	}
+	return entities
+}
+
 // AllVariables returns all the variables that were considered by the solver to obtain (or not)
 // a solution. Note: This is only be present if the AddAllVariablesToSolution option is passed in to the
 // Solve call that generated the solution.
@@ -47,6 +64,44 @@ func (s *Solution) AllVariables() []deppy.Variable {
 	return s.variables
 }
 
+// Explain returns the applied constraints that account for the
+// selection of the variable identified by the identifier: the anchoring
+// constraints of the variable itself and the constraints of other
+// selected variables that list it among their candidates. Each applied
+// constraint carries the metadata of its constraint, if any. It returns
+// nil if the variable was not selected.
+func (s *Solution) Explain(identifier deppy.Identifier) []deppy.AppliedConstraint {
+	if !s.IsSelected(identifier) {
+		return nil
+	}
+	var result []deppy.AppliedConstraint
+	for _, variable := range s.considered {
+		self := variable.Identifier() == identifier
+		if !self && !s.IsSelected(variable.Identifier()) {
+			continue
+		}
+		for _, c := range variable.Constraints() {
+			if (self && c.Anchor()) || (!self && orders(c, identifier)) {
+				result = append(result, deppy.AppliedConstraint{
+					Variable:   variable,
+					Constraint: c,
+					Metadata:   deppy.MetadataOf(c),
+				})
+			}
+		}
+	}
+	return result
+}
+
+func orders(c deppy.Constraint, identifier deppy.Identifier) bool {
+	for _, id := range c.Order() {
+		if id == identifier {
+			return true
+		}
+	}
+	return false
+}
+
 type solutionOptions struct {
 	addVariablesToSolution bool
 }
@@ -91,7 +146,9 @@ func NewDeppySolver(entitySource input.EntitySource, variableSource input.Variab
 func (d DeppySolver) Solve(ctx context.Context, options ...Option) (*Solution, error) {
 	solutionOpts := defaultSolutionOptions().apply(options...)
 
-	vars, err := d.variableSource.GetVariables(ctx, d.entitySource)
+	// variable sources must all see the same entities, even if the
+	// entity source is updated during the resolution
+	vars, err := d.variableSource.GetVariables(ctx, input.SnapshotOf(d.entitySource))
 	if err != nil {
 		return nil, err
 	}
@@ -111,7 +168,7 @@ func (d DeppySolver) Solve(ctx context.Context, options ...Option) (*Solution, e
 		selectionMap[variable.Identifier()] = variable
 	}
 
-	solution := &Solution{selection: selectionMap}
+	solution := &Solution{selection: selectionMap, considered: vars}
 	if err != nil {
 		unsatError := deppy.NotSatisfiable{}
 		errors.As(err, &unsatError)
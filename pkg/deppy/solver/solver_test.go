@@ -2,6 +2,7 @@ package solver_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"testing"
 
@@ -210,6 +211,99 @@ var _ = Describe("Entity", func() {
 			deppy.Identifier("6"): Equal(input.NewSimpleVariable("6")),
 		}))
 	})
+
+	It("should expose constraint metadata in resolution errors", func() {
+		mandatory := constraint.WithMetadata(constraint.Mandatory(), deppy.Metadata{Source: "user", Reason: "requested by user"})
+		prohibited := constraint.WithSource(constraint.Prohibited(), "policy")
+		variables := []deppy.Variable{
+			input.NewSimpleVariable("1", mandatory),
+			input.NewSimpleVariable("2", prohibited),
+		}
+		variables[0].(*input.SimpleVariable).AddConstraint(constraint.Dependency("2"))
+		s := NewEntitySource(variables)
+		so, err := solver.NewDeppySolver(s, s)
+		Expect(err).ToNot(HaveOccurred())
+		solution, err := so.Solve(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(solution.Error()).To(HaveOccurred())
+		Expect(solution.Error().Error()).To(ContainSubstring("1 is mandatory (requested by user, from user)"))
+		Expect(solution.Error().Error()).To(ContainSubstring("(from policy)"))
+		var unsat deppy.NotSatisfiable
+		Expect(errors.As(solution.Error(), &unsat)).To(BeTrue())
+		Expect(unsat).To(ContainElement(deppy.AppliedConstraint{
+			Variable:   variables[0],
+			Constraint: mandatory,
+			Metadata:   deppy.Metadata{Source: "user", Reason: "requested by user"},
+		}))
+	})
+
+	It("should explain why a variable was selected", func() {
+		mandatory := constraint.WithReason(constraint.Mandatory(), "requested by user")
+		dependency := constraint.WithSource(constraint.Dependency("2", "3"), "catalog")
+		variables := []deppy.Variable{
+			input.NewSimpleVariable("1", mandatory, dependency),
+			input.NewSimpleVariable("2"),
+			input.NewSimpleVariable("3"),
+		}
+		s := NewEntitySource(variables)
+		so, err := solver.NewDeppySolver(s, s)
+		Expect(err).ToNot(HaveOccurred())
+		solution, err := so.Solve(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(solution.Explain("1")).To(Equal([]deppy.AppliedConstraint{
+			{Variable: variables[0], Constraint: mandatory, Metadata: deppy.Metadata{Reason: "requested by user"}},
+		}))
+		Expect(solution.Explain("2")).To(Equal([]deppy.AppliedConstraint{
+			{Variable: variables[0], Constraint: dependency, Metadata: deppy.Metadata{Source: "catalog"}},
+		}))
+		Expect(solution.Explain("3")).To(BeNil())
+	})
+
+	It("should return the entities of the selected variables", func() {
+		entity1 := input.NewEntity("1", map[string]string{"version": "1"})
+		entity2 := input.NewEntity("2", map[string]string{"version": "2"})
+		variables := []deppy.Variable{
+			input.NewEntityVariable(entity1, constraint.Mandatory(), constraint.Dependency("2")),
+			input.NewEntityVariable(entity2),
+			input.NewEntityVariable(input.NewEntity("3", nil)),
+			input.NewSimpleVariable("4", constraint.Mandatory()),
+		}
+		s := NewEntitySource(variables)
+		so, err := solver.NewDeppySolver(s, s)
+		Expect(err).ToNot(HaveOccurred())
+		solution, err := so.Solve(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(solution.SelectedEntities()).To(Equal(map[deppy.Identifier]*input.Entity{
+			"1": entity1,
+			"2": entity2,
+		}))
+	})
+
+	It("should let a variable source add constraints to the variables of another", func() {
+		s := NewEntitySource([]deppy.Variable{
+			input.NewSimpleVariable("1", constraint.Mandatory(), constraint.Dependency("2", "3")),
+			input.NewSimpleVariable("2"),
+			input.NewSimpleVariable("3"),
+		})
+		pins := &EntitySourceStruct{variables: []deppy.Variable{
+			input.NewSimpleVariable("2", constraint.Prohibited()),
+		}}
+		aggregate := input.NewAggregateVariableSource(s, pins)
+
+		so, err := solver.NewDeppySolver(s, aggregate)
+		Expect(err).ToNot(HaveOccurred())
+		_, err = so.Solve(context.Background())
+		Expect(err).To(MatchError(input.DuplicateVariable("2")))
+
+		aggregate.Merge = input.UnionConstraints
+		solution, err := so.Solve(context.Background())
+		Expect(err).ToNot(HaveOccurred())
+		Expect(solution.Error()).ToNot(HaveOccurred())
+		Expect(solution.SelectedVariables()).To(MatchAllKeys(Keys{
+			deppy.Identifier("1"): Not(BeNil()),
+			deppy.Identifier("3"): Not(BeNil()),
+		}))
+	})
 })
 
 var _ input.VariableSource = &FailingVariableSource{}
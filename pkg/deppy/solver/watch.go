@@ -0,0 +1,123 @@
+package solver
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+)
+
+// SolutionUpdate is sent by DeppySolver.Watch with either a new
+// Solution or the error that prevented finding one.
+type SolutionUpdate struct {
+	Solution *Solution
+	Err      error
+}
+
+// Watch solves the problem once, and again whenever the entities of the
+// solver's entity source change, which requires it to be an
+// input.WatchableEntitySource. Changes are debounced: the problem is
+// solved again once no change has been made for the debounce duration.
+//
+// The returned channel receives the first Solution, then every
+// Solution whose selection differs from the previous one sent,
+// including a change from satisfiable to unsatisfiable or back. Errors
+// are always sent, and the Solution following an error is sent
+// regardless of its selection. The channel is closed once ctx is done.
+func (d DeppySolver) Watch(ctx context.Context, debounce time.Duration, options ...Option) (<-chan SolutionUpdate, error) {
+	watchable, ok := d.entitySource.(input.WatchableEntitySource)
+	if !ok {
+		return nil, errors.New("entity source is not watchable")
+	}
+	// watch before solving, so that no change is missed
+	events := watchable.Watch(ctx)
+
+	updates := make(chan SolutionUpdate)
+	go func() {
+		defer close(updates)
+
+		var (
+			last    string
+			emitted bool
+		)
+		send := func(update SolutionUpdate) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case updates <- update:
+				return true
+			}
+		}
+		solve := func() bool {
+			solution, err := d.Solve(ctx, options...)
+			if err != nil {
+				if ctx.Err() != nil {
+					return false
+				}
+				emitted = false
+				return send(SolutionUpdate{Err: err})
+			}
+			key := selectionKey(solution)
+			if emitted && key == last {
+				return true
+			}
+			last, emitted = key, true
+			return send(SolutionUpdate{Solution: solution})
+		}
+
+		if !solve() {
+			return
+		}
+		var (
+			timer *time.Timer
+			fire  <-chan time.Time
+		)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case _, ok := <-events:
+				if !ok {
+					return
+				}
+				switch {
+				case timer == nil:
+					timer = time.NewTimer(debounce)
+					defer timer.Stop()
+				case fire == nil:
+					timer.Reset(debounce)
+				default:
+					if !timer.Stop() {
+						<-timer.C
+					}
+					timer.Reset(debounce)
+				}
+				fire = timer.C
+			case <-fire:
+				fire = nil
+				if !solve() {
+					return
+				}
+			}
+		}
+	}()
+	return updates, nil
+}
+
+// selectionKey returns a string identifying the selection of solution,
+// and whether it is satisfiable.
+func selectionKey(solution *Solution) string {
+	ids := make([]string, 0, len(solution.selection))
+	for id := range solution.selection {
+		ids = append(ids, string(id))
+	}
+	sort.Strings(ids)
+	sat := "sat"
+	if len(solution.err) > 0 {
+		sat = "unsat"
+	}
+	return sat + "\x00" + strings.Join(ids, "\x00")
+}
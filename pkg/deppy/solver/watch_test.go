@@ -0,0 +1,156 @@
+package solver_test
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/operator-framework/deppy/pkg/deppy"
+	"github.com/operator-framework/deppy/pkg/deppy/constraint"
+	"github.com/operator-framework/deppy/pkg/deppy/input"
+	"github.com/operator-framework/deppy/pkg/deppy/solver"
+)
+
+// entityVariables creates a variable for every entity, mandatory if
+// the entity has the "mandatory" property and conflicting with the
+// entity named by its "conflict" property, if any.
+type entityVariables struct{}
+
+func (entityVariables) GetVariables(ctx context.Context, source input.EntitySource) ([]deppy.Variable, error) {
+	var variables []deppy.Variable
+	err := source.Iterate(ctx, func(entity *input.Entity) error {
+		var constraints []deppy.Constraint
+		if _, ok := entity.Properties["mandatory"]; ok {
+			constraints = append(constraints, constraint.Mandatory())
+		}
+		if id, ok := entity.Properties["conflict"]; ok {
+			constraints = append(constraints, constraint.Conflict(deppy.Identifier(id)))
+		}
+		variables = append(variables, input.NewSimpleVariable(entity.ID, constraints...))
+		return nil
+	})
+	return variables, err
+}
+
+func selected(update solver.SolutionUpdate) []deppy.Identifier {
+	Expect(update.Err).NotTo(HaveOccurred())
+	var ids []deppy.Identifier
+	for id := range update.Solution.SelectedVariables() {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+var _ = Describe("Watch", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		source *input.MutableEntitySource
+	)
+
+	BeforeEach(func() {
+		ctx, cancel = context.WithCancel(context.Background())
+		DeferCleanup(func() { cancel() })
+		source = input.NewMutableEntitySource(map[deppy.Identifier]input.Entity{
+			"a": *input.NewEntity("a", map[string]string{"mandatory": ""}),
+			"b": *input.NewEntity("b", nil),
+		})
+	})
+
+	It("should require a watchable entity source", func() {
+		s := NewEntitySource(nil)
+		so, err := solver.NewDeppySolver(s, s)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = so.Watch(ctx, time.Millisecond)
+		Expect(err).To(MatchError("entity source is not watchable"))
+	})
+
+	It("should send new solutions when the selection changes", func() {
+		so, err := solver.NewDeppySolver(source, entityVariables{})
+		Expect(err).NotTo(HaveOccurred())
+		updates, err := so.Watch(ctx, 20*time.Millisecond)
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(selected(<-updates)).To(ConsistOf(deppy.Identifier("a")))
+
+		// the selection doesn't change
+		source.Set(*input.NewEntity("c", nil))
+		Consistently(updates, 100*time.Millisecond).ShouldNot(Receive())
+
+		// a burst of changes yields a single solution
+		source.Set(*input.NewEntity("b", map[string]string{"mandatory": ""}))
+		source.Set(*input.NewEntity("c", map[string]string{"mandatory": ""}))
+		var update solver.SolutionUpdate
+		Eventually(updates).Should(Receive(&update))
+		Expect(selected(update)).To(ConsistOf(deppy.Identifier("a"), deppy.Identifier("b"), deppy.Identifier("c")))
+		Consistently(updates, 100*time.Millisecond).ShouldNot(Receive())
+
+		// becoming unsatisfiable is a change
+		source.Set(*input.NewEntity("c", map[string]string{"mandatory": "", "conflict": "a"}))
+		Eventually(updates).Should(Receive(&update))
+		Expect(update.Err).NotTo(HaveOccurred())
+		Expect(update.Solution.Error()).To(HaveOccurred())
+
+		cancel()
+		Eventually(updates).Should(BeClosed())
+	})
+	It("should send a change between satisfiable and unsatisfiable with the same selection", func() {
+		source.Delete("a")
+		so, err := solver.NewDeppySolver(source, entityVariables{})
+		Expect(err).NotTo(HaveOccurred())
+		updates, err := so.Watch(ctx, 20*time.Millisecond)
+		Expect(err).NotTo(HaveOccurred())
+
+		var update solver.SolutionUpdate
+		Eventually(updates).Should(Receive(&update))
+		Expect(selected(update)).To(BeEmpty())
+		Expect(update.Solution.Error()).NotTo(HaveOccurred())
+
+		// nothing is selected either way
+		source.Set(*input.NewEntity("b", map[string]string{"mandatory": "", "conflict": "b"}))
+		Eventually(updates).Should(Receive(&update))
+		Expect(selected(update)).To(BeEmpty())
+		Expect(update.Solution.Error()).To(HaveOccurred())
+
+		source.Set(*input.NewEntity("b", nil))
+		Eventually(updates).Should(Receive(&update))
+		Expect(selected(update)).To(BeEmpty())
+		Expect(update.Solution.Error()).NotTo(HaveOccurred())
+	})
+})
+
+// mutatingVariables deletes entity "b" from source after reading the
+// entities a first time, and reports what it saw the second time.
+type mutatingVariables struct {
+	source *input.MutableEntitySource
+	seen   []deppy.Identifier
+}
+
+func (m *mutatingVariables) GetVariables(ctx context.Context, source input.EntitySource) ([]deppy.Variable, error) {
+	variables, err := entityVariables{}.GetVariables(ctx, source)
+	if err != nil {
+		return nil, err
+	}
+	m.source.Delete("b")
+	el, err := source.Filter(ctx, func(*input.Entity) bool { return true })
+	m.seen = el.CollectIds()
+	return variables, err
+}
+
+var _ = Describe("Snapshots", func() {
+	It("should solve against a single snapshot of the entities", func() {
+		source := input.NewMutableEntitySource(map[deppy.Identifier]input.Entity{
+			"a": *input.NewEntity("a", nil),
+			"b": *input.NewEntity("b", nil),
+		})
+		variables := &mutatingVariables{source: source}
+		so, err := solver.NewDeppySolver(source, variables)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = so.Solve(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(variables.seen).To(Equal([]deppy.Identifier{"a", "b"}))
+		Expect(source.Get(context.Background(), "b")).To(BeNil())
+	})
+})
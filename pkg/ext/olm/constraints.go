@@ -2,12 +2,12 @@ package olm
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"regexp"
 	"strings"
 
 	"github.com/blang/semver/v4"
-	"github.com/tidwall/gjson"
 
 	"github.com/operator-framework/deppy/pkg/deppy/input"
 
@@ -35,8 +35,8 @@ type requirePackage struct {
 }
 
 func (r *requirePackage) GetVariables(ctx context.Context, entitySource input.EntitySource) ([]deppy.Variable, error) {
-	resultSet, err := entitySource.Filter(ctx, input.And(
-		withPackageName(r.packageName),
+	key := fmt.Sprintf("olm: package %q, version %q, channel %q", r.packageName, r.versionRange, r.channel)
+	resultSet, err := filterPackage(ctx, entitySource, key, r.packageName, input.And(
 		withinVersion(r.versionRange),
 		withChannel(r.channel)))
 	if err != nil || len(resultSet) == 0 {
@@ -65,10 +65,23 @@ type subjectFormatFn func(key string) deppy.Identifier
 type uniqueness struct {
 	subject   subjectFormatFn
 	groupByFn input.GroupByFunction
+	// key names groupByFn for input.GroupByKey
+	key string
+	// property, if set, is the property whose values groupByFn
+	// returns, for sources implementing input.PropertyLookup
+	property string
 }
 
 func (u *uniqueness) GetVariables(ctx context.Context, entitySource input.EntitySource) ([]deppy.Variable, error) {
-	resultSet, err := entitySource.GroupBy(ctx, u.groupByFn)
+	var (
+		resultSet input.EntityListMap
+		err       error
+	)
+	if lookup, ok := entitySource.(input.PropertyLookup); ok && u.property != "" {
+		resultSet, err = lookup.GroupByProperty(ctx, u.property)
+	} else {
+		resultSet, err = input.GroupByKey(ctx, entitySource, u.key, u.groupByFn)
+	}
 	if err != nil || len(resultSet) == 0 {
 		return nil, err
 	}
@@ -85,6 +98,7 @@ func GVKUniqueness() input.VariableSource {
 	return &uniqueness{
 		subject:   uniquenessSubjectFormat,
 		groupByFn: gvkGroupFunction,
+		key:       "olm: by gvk",
 	}
 }
 
@@ -93,6 +107,8 @@ func PackageUniqueness() input.VariableSource {
 	return &uniqueness{
 		subject:   uniquenessSubjectFormat,
 		groupByFn: packageGroupFunction,
+		key:       "olm: by package",
+		property:  PropertyOLMPackageName,
 	}
 }
 
@@ -109,7 +125,8 @@ type packageDependency struct {
 }
 
 func (p *packageDependency) GetVariables(ctx context.Context, entitySource input.EntitySource) ([]deppy.Variable, error) {
-	entities, err := entitySource.Filter(ctx, input.And(withPackageName(p.packageName), withinVersion(p.versionRange)))
+	key := fmt.Sprintf("olm: package %q, version %q", p.packageName, p.versionRange)
+	entities, err := filterPackage(ctx, entitySource, key, p.packageName, withinVersion(p.versionRange))
 	if err != nil || len(entities) == 0 {
 		return nil, err
 	}
@@ -136,7 +153,8 @@ type gvkDependency struct {
 }
 
 func (g *gvkDependency) GetVariables(ctx context.Context, entitySource input.EntitySource) ([]deppy.Variable, error) {
-	entities, err := entitySource.Filter(ctx, input.And(withExportsGVK(g.group, g.version, g.kind)))
+	key := fmt.Sprintf("olm: gvk %q, %q, %q", g.group, g.version, g.kind)
+	entities, err := input.FilterByKey(ctx, entitySource, key, input.And(withExportsGVK(g.group, g.version, g.kind)))
 	if err != nil || len(entities) == 0 {
 		return nil, err
 	}
@@ -154,6 +172,58 @@ func GVKDependency(subject deppy.Identifier, group string, version string, kind
 	}
 }
 
+var _ input.VariableSource = &bundles{}
+
+type bundles struct{}
+
+func (b *bundles) GetVariables(ctx context.Context, entitySource input.EntitySource) ([]deppy.Variable, error) {
+	entities, err := input.FilterByKey(ctx, entitySource, "olm: bundles", hasPackageName)
+	if err != nil || len(entities) == 0 {
+		return nil, err
+	}
+	entities = entities.Sort(byChannelAndVersion)
+	variables := make([]deppy.Variable, 0, len(entities))
+	for i := range entities {
+		variables = append(variables, input.NewEntityVariable(&entities[i]))
+	}
+	return variables, nil
+}
+
+// Bundles generates a variable for every bundle, that is every entity with a package name, for the
+// other constraint generators to refer to. The variables carry their entity, so that the selected
+// bundles can be read from the solution with SelectedEntities.
+func Bundles() input.VariableSource {
+	return &bundles{}
+}
+
+// filterPackage returns the entities of the package with the given name
+// that satisfy filter. Sources implementing input.PropertyLookup, such
+// as an input.IndexedEntitySource indexing PropertyOLMPackageName, are
+// asked for the entities of the package only; other sources are
+// filtered with a query named by key.
+func filterPackage(ctx context.Context, entitySource input.EntitySource, key string, packageName string, filter input.Predicate) (input.EntityList, error) {
+	lookup, ok := entitySource.(input.PropertyLookup)
+	if !ok {
+		return input.FilterByKey(ctx, entitySource, key, input.And(withPackageName(packageName), filter))
+	}
+	entities, err := lookup.Lookup(ctx, PropertyOLMPackageName, packageName)
+	if err != nil {
+		return nil, err
+	}
+	resultSet := input.EntityList{}
+	for i := range entities {
+		if filter(&entities[i]) {
+			resultSet = append(resultSet, entities[i])
+		}
+	}
+	return resultSet, nil
+}
+
+func hasPackageName(entity *input.Entity) bool {
+	_, ok := entity.Properties[PropertyOLMPackageName]
+	return ok
+}
+
 func withPackageName(packageName string) input.Predicate {
 	return func(entity *input.Entity) bool {
 		if pkgName, ok := entity.Properties[PropertyOLMPackageName]; ok {
@@ -164,16 +234,12 @@ func withPackageName(packageName string) input.Predicate {
 }
 
 func withinVersion(semverRange string) input.Predicate {
+	vrange, err := semver.ParseRange(semverRange)
 	return func(entity *input.Entity) bool {
-		if v, ok := entity.Properties[PropertyOLMVersion]; ok {
-			vrange, err := semver.ParseRange(semverRange)
-			if err != nil {
-				return false
-			}
-			version, err := semver.Parse(v)
-			if err != nil {
-				return false
-			}
+		if err != nil {
+			return false
+		}
+		if version, ok := entity.SemverProperty(PropertyOLMVersion); ok {
 			return vrange(version)
 		}
 		return false
@@ -194,19 +260,81 @@ func withChannel(channel string) input.Predicate {
 
 func withExportsGVK(group string, version string, kind string) input.Predicate {
 	return func(entity *input.Entity) bool {
-		if g, ok := entity.Properties[PropertyOLMGVK]; ok {
-			for _, gvk := range gjson.Parse(g).Array() {
-				if gjson.Get(gvk.String(), "group").String() == group && gjson.Get(gvk.String(), "version").String() == version && gjson.Get(gvk.String(), "kind").String() == kind {
-					return true
-				}
+		for _, gvk := range providedGVKs(entity) {
+			if gvk.group == group && gvk.version == version && gvk.kind == kind {
+				return true
 			}
 		}
 		return false
 	}
 }
 
+type gvk struct {
+	group   string
+	version string
+	kind    string
+}
+
+// providedGVKs returns the gvks listed by the olm.gvk property of
+// entity: a list of objects with group, version and kind fields, or a
+// single such object, either typed or encoded as a JSON string. JSON
+// strings are decoded on every call, or ignored if malformed; sources
+// decode them once when loading entities with DecodeProperties.
+func providedGVKs(entity *input.Entity) []gvk {
+	value, ok := entity.Value(PropertyOLMGVK)
+	if !ok {
+		return nil
+	}
+	if s, ok := value.AsString(); ok {
+		if err := json.Unmarshal([]byte(s), &value); err != nil {
+			return []gvk{}
+		}
+	}
+	return gvksOf(value)
+}
+
+// DecodeProperties replaces the JSON encoded olm.gvk property of entity,
+// if any, by its typed value, so that the gvk predicates don't decode it
+// again for every query. It fails if the property is malformed. It is
+// meant to be applied to entities as they are loaded, for instance with
+// input.WithEntityDecoder.
+func DecodeProperties(entity *input.Entity) error {
+	value, ok := entity.Value(PropertyOLMGVK)
+	if !ok {
+		return nil
+	}
+	s, ok := value.AsString()
+	if !ok {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(s), &value); err != nil {
+		return fmt.Errorf("invalid %s property: %w", PropertyOLMGVK, err)
+	}
+	entity.SetValue(PropertyOLMGVK, value)
+	return nil
+}
+
+// gvksOf returns the gvks of a typed olm.gvk property value.
+func gvksOf(value input.Value) []gvk {
+	values, ok := value.AsList()
+	if !ok {
+		values = []input.Value{value}
+	}
+	gvks := make([]gvk, 0, len(values))
+	for _, value := range values {
+		field := func(name string) string {
+			f, _ := value.Get(name)
+			s, _ := f.AsString()
+			return s
+		}
+		gvks = append(gvks, gvk{group: field("group"), version: field("version"), kind: field("kind")})
+	}
+	return gvks
+}
+
 // byChannelAndVersion is an entity sort function that orders the entities in
-// package, channel (default channel at the head), and inverse version (higher versions on top)
+// package, inverse catalog priority (see input.PriorityOf), channel (default channel at the head),
+// and inverse version (higher versions on top)
 // if a property does not exist for one of the entities, the one missing the property is pushed down
 // if both entities are missing the same property they are ordered by id
 func byChannelAndVersion(e1 *input.Entity, e2 *input.Entity) bool {
@@ -218,6 +346,11 @@ func byChannelAndVersion(e1 *input.Entity, e2 *input.Entity) bool {
 		return pkgOrder < 0
 	}
 
+	// then prefer entities from higher priority catalogs
+	if p1, p2 := input.PriorityOf(e1), input.PriorityOf(e2); p1 != p2 {
+		return p1 > p2
+	}
+
 	// then sort by channel order with default channel at the start and all other channels in lexical order
 	e1DefaultChannel := getPropertyOrNotFound(e1, PropertyOLMDefaultChannel)
 	e2DefaultChannel := getPropertyOrNotFound(e2, PropertyOLMDefaultChannel)
@@ -270,21 +403,17 @@ func byChannelAndVersion(e1 *input.Entity, e2 *input.Entity) bool {
 }
 
 func gvkGroupFunction(entity *input.Entity) []string {
-	if gvks, ok := entity.Properties[PropertyOLMGVK]; ok {
-		gvkArray := gjson.Parse(gvks).Array()
-		keys := make([]string, 0, len(gvkArray))
-		for _, val := range gvkArray {
-			var group = val.Get("group")
-			var version = val.Get("version")
-			var kind = val.Get("kind")
-			if group.String() != "" && version.String() != "" && kind.String() != "" {
-				gvk := fmt.Sprintf("%s/%s/%s", group, version, kind)
-				keys = append(keys, gvk)
-			}
+	gvks := providedGVKs(entity)
+	if gvks == nil {
+		return nil
+	}
+	keys := make([]string, 0, len(gvks))
+	for _, gvk := range gvks {
+		if gvk.group != "" && gvk.version != "" && gvk.kind != "" {
+			keys = append(keys, fmt.Sprintf("%s/%s/%s", gvk.group, gvk.version, gvk.kind))
 		}
-		return keys
 	}
-	return nil
+	return keys
 }
 
 func packageGroupFunction(entity *input.Entity) []string {
@@ -13,6 +13,7 @@ import (
 
 	"github.com/operator-framework/deppy/pkg/deppy"
 	"github.com/operator-framework/deppy/pkg/deppy/input"
+	"github.com/operator-framework/deppy/pkg/deppy/solver"
 
 	. "github.com/onsi/gomega/gstruct"
 
@@ -97,6 +98,19 @@ func (t MockQuerier) Iterate(_ context.Context, id input.IteratorFunction) error
 	return nil
 }
 
+// LookupOnlyQuerier wraps an IndexedEntitySource, failing queries that
+// evaluate a predicate against every entity
+type LookupOnlyQuerier struct {
+	*input.IndexedEntitySource
+}
+
+func (LookupOnlyQuerier) Filter(_ context.Context, _ input.Predicate) (input.EntityList, error) {
+	return nil, errors.New("unexpected Filter")
+}
+func (LookupOnlyQuerier) GroupBy(_ context.Context, _ input.GroupByFunction) (input.EntityListMap, error) {
+	return nil, errors.New("unexpected GroupBy")
+}
+
 var _ = Describe("Constraints", func() {
 	Context("requirePackage", func() {
 		Describe("GetVariables", func() {
@@ -251,6 +265,15 @@ var _ = Describe("Constraints", func() {
 				Expect(satVars[2].Identifier().String()).To(Equal("cool-package-3 uniqueness"))
 				Expect(satVars[2].Constraints()[0].String("test-pkg")).To(Equal("test-pkg permits at most 1 of cool-package-3-entity"))
 			})
+			It("shares grouped entities through a memoizing entity source", func() {
+				memo := input.NewMemoizingEntitySource(mockQuerier)
+				for i := 0; i < 2; i++ {
+					satVars, err := olm.PackageUniqueness().GetVariables(ctx, memo)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(satVars).Should(HaveLen(3))
+				}
+				Expect(memo.Stats()).To(Equal(input.MemoStats{Hits: 1, Misses: 1}))
+			})
 			It("forwards any error given by the entity querier", func() {
 				mockQuerier.testError = errors.New("oh no")
 				satVars, err := olm.PackageUniqueness().GetVariables(ctx, mockQuerier)
@@ -388,6 +411,40 @@ var _ = Describe("Constraints", func() {
 				msg := satVars[0].Constraints()[0].String("test-pkg")
 				Expect(msg).To(Equal("test-pkg requires at least one of cool-package-1-entity, cool-package-2-0-entity, cool-package-3-entity"))
 			})
+			It("reads typed gvk values as well as JSON encoded ones", func() {
+				gvk := func(group string) input.Value {
+					return input.ObjectValue(map[string]input.Value{
+						"group":   input.StringValue(group),
+						"version": input.StringValue("my-version"),
+						"kind":    input.StringValue("my-kind"),
+					})
+				}
+				mockQuerier.testEntityList = append(mockQuerier.testEntityList,
+					*input.NewEntityWithValues("typed-list-entity", map[string]input.Value{
+						olm.PropertyOLMGVK: input.ListValue(gvk("my-other-group"), gvk("my-group")),
+					}),
+					*input.NewEntityWithValues("typed-object-entity", map[string]input.Value{
+						olm.PropertyOLMGVK: gvk("my-group"),
+					}),
+				)
+				satVars, err := olm.GVKDependency("cool-package-2-dep", "my-group", "my-version", "my-kind").GetVariables(ctx, mockQuerier)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(satVars[0].Constraints()[0].String("test-pkg")).To(Equal("test-pkg requires at least one of cool-package-1-entity, cool-package-2-0-entity, cool-package-3-entity, typed-list-entity, typed-object-entity"))
+			})
+			It("reads gvk values decoded with DecodeProperties", func() {
+				for i := range mockQuerier.testEntityList {
+					Expect(olm.DecodeProperties(&mockQuerier.testEntityList[i])).To(Succeed())
+				}
+				gvk, ok := mockQuerier.testEntityList[0].ObjectProperty(olm.PropertyOLMGVK)
+				Expect(ok).To(BeTrue())
+				Expect(gvk).To(HaveKeyWithValue("group", input.StringValue("my-group")))
+
+				satVars, err := olm.GVKDependency("cool-package-2-dep", "my-group", "my-version", "my-kind").GetVariables(ctx, mockQuerier)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(satVars[0].Constraints()[0].String("test-pkg")).To(Equal("test-pkg requires at least one of cool-package-1-entity, cool-package-2-0-entity, cool-package-3-entity"))
+
+				Expect(olm.DecodeProperties(input.NewEntity("malformed", map[string]string{olm.PropertyOLMGVK: "abcdefg"}))).To(MatchError(HavePrefix("invalid olm.gvk property: ")))
+			})
 			It("forwards any error encountered by the entity querier", func() {
 				mockQuerier.testError = errors.New("oh no")
 				satVars, err := olm.GVKDependency("cool-package-2-dep", "my-group", "my-version", "my-kind").GetVariables(ctx, mockQuerier)
@@ -396,6 +453,83 @@ var _ = Describe("Constraints", func() {
 				Expect(satVars).Should(HaveLen(0))
 			})
 		})
+		Describe("Bundles", func() {
+			var (
+				ctx         context.Context
+				mockQuerier MockQuerier
+			)
+			BeforeEach(func() {
+				ctx = context.Background()
+				mockQuerier = MockQuerier{
+					testError:      nil,
+					testEntityList: defaultTestEntityList(),
+				}
+			})
+			It("returns a satVar carrying its entity for every bundle", func() {
+				mockQuerier.testEntityList = append(mockQuerier.testEntityList, *input.NewEntity("not-a-bundle", map[string]string{}))
+				satVars, err := olm.Bundles().GetVariables(ctx, mockQuerier)
+				Expect(err).NotTo(HaveOccurred())
+				ids := make([]deppy.Identifier, 0, len(satVars))
+				for _, satVar := range satVars {
+					ids = append(ids, satVar.Identifier())
+					Expect(satVar.Constraints()).To(BeEmpty())
+					entity, ok := input.EntityOf(satVar)
+					Expect(ok).To(BeTrue())
+					Expect(entity.ID).To(Equal(satVar.Identifier()))
+				}
+				Expect(ids).To(Equal([]deppy.Identifier{"cool-package-1-entity", "cool-package-2-1-entity", "cool-package-2-0-entity", "cool-package-3-entity"}))
+			})
+			It("forwards any error encountered by the entity querier", func() {
+				mockQuerier.testError = errors.New("oh no")
+				satVars, err := olm.Bundles().GetVariables(ctx, mockQuerier)
+				Expect(err).To(MatchError("oh no"))
+				Expect(satVars).Should(HaveLen(0))
+			})
+			It("lets the selected bundles be read from the solution", func() {
+				entities := map[deppy.Identifier]input.Entity{}
+				for _, entity := range defaultTestEntityList() {
+					entities[entity.ID] = entity
+				}
+				entitySource := input.NewCacheQuerier(entities)
+				variableSource := input.NewAggregateVariableSource(
+					olm.Bundles(),
+					olm.RequirePackage("cool-package-2", ">=2.0.0", "channel-1"),
+					olm.PackageUniqueness(),
+				)
+				s, err := solver.NewDeppySolver(entitySource, variableSource)
+				Expect(err).NotTo(HaveOccurred())
+				solution, err := s.Solve(ctx)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(solution.Error()).NotTo(HaveOccurred())
+				selected := solution.SelectedEntities()
+				Expect(selected).To(HaveLen(1))
+				Expect(selected).To(HaveKey(deppy.Identifier("cool-package-2-1-entity")))
+				Expect(selected["cool-package-2-1-entity"].Properties).To(HaveKeyWithValue(olm.PropertyOLMVersion, "2.1.0"))
+			})
+			It("reads the selected bundles of variables merged with other sources", func() {
+				entities := map[deppy.Identifier]input.Entity{}
+				for _, entity := range defaultTestEntityList() {
+					entities[entity.ID] = entity
+				}
+				entitySource := input.NewCacheQuerier(entities)
+				variableSource := input.NewAggregateVariableSource(
+					olm.GVKDependency("cool-package-2-1-entity", "my-group", "my-version", "my-kind"),
+					olm.RequirePackage("cool-package-2", ">=2.1.0", "channel-1"),
+					olm.Bundles(),
+					olm.PackageUniqueness(),
+				)
+				variableSource.Merge = input.UnionConstraints
+				s, err := solver.NewDeppySolver(entitySource, variableSource)
+				Expect(err).NotTo(HaveOccurred())
+				solution, err := s.Solve(ctx)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(solution.Error()).NotTo(HaveOccurred())
+				selected := solution.SelectedEntities()
+				Expect(selected).To(HaveLen(2))
+				Expect(selected).To(HaveKey(deppy.Identifier("cool-package-2-1-entity")))
+				Expect(selected).To(HaveKey(deppy.Identifier("cool-package-1-entity")))
+			})
+		})
 	})
 	Context("byChannelAndVersion", func() {
 		var (
@@ -408,6 +542,34 @@ var _ = Describe("Constraints", func() {
 				testError: nil,
 			}
 		})
+		Describe("with an indexed entity source", func() {
+			var (
+				ctx     context.Context
+				querier LookupOnlyQuerier
+			)
+			BeforeEach(func() {
+				ctx = context.Background()
+				entities := map[deppy.Identifier]input.Entity{}
+				for _, entity := range defaultTestEntityList() {
+					entities[entity.ID] = entity
+				}
+				querier = LookupOnlyQuerier{input.NewIndexedEntitySource(entities, olm.PropertyOLMPackageName)}
+			})
+			It("looks up the entities of required packages by package name", func() {
+				satVars, err := olm.RequirePackage("cool-package-2", "<=2.0.3", "channel-1").GetVariables(ctx, querier)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(satVars[0].Constraints()[1].String("test-pkg")).To(Equal("test-pkg requires at least one of cool-package-2-0-entity"))
+
+				satVars, err = olm.PackageDependency("cool-package-2-dep", "cool-package-2", "<=3.0.2").GetVariables(ctx, querier)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(satVars[0].Constraints()[0].String("test-pkg")).To(Equal("test-pkg requires at least one of cool-package-2-1-entity, cool-package-2-0-entity"))
+			})
+			It("groups entities by package name with the index", func() {
+				satVars, err := olm.PackageUniqueness().GetVariables(ctx, querier)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(satVars).Should(HaveLen(3))
+			})
+		})
 		DescribeTable("package name ordering", func(pkg1NameKey string, pkg2NameKey string, matchElements Elements) {
 			mockQuerier.testEntityList = input.EntityList{
 				*input.NewEntity("cool-package-entity-1", map[string]string{
@@ -513,6 +675,33 @@ var _ = Describe("Constraints", func() {
 					"8": Equal("cool-package-1-channelless-1.1-entity"),
 				}))
 			})
+
+			It("orders sat vars with identical packageName from higher priority catalogs first", func() {
+				mockQuerier.testEntityList = input.EntityList{
+					*input.NewEntity("community-1.2-entity", map[string]string{
+						olm.PropertyOLMPackageName: "cool-package-1",
+						olm.PropertyOLMVersion:     "1.2.0",
+						olm.PropertyOLMChannel:     "channel-1",
+						input.PropertyPriority:     "0",
+					}),
+					*input.NewEntity("certified-1.0-entity", map[string]string{
+						olm.PropertyOLMPackageName: "cool-package-1",
+						olm.PropertyOLMVersion:     "1.0.0",
+						olm.PropertyOLMChannel:     "channel-1",
+						input.PropertyPriority:     "10",
+					}),
+					*input.NewEntity("certified-1.1-entity", map[string]string{
+						olm.PropertyOLMPackageName: "cool-package-1",
+						olm.PropertyOLMVersion:     "1.1.0",
+						olm.PropertyOLMChannel:     "channel-1",
+						input.PropertyPriority:     "10",
+					}),
+				}
+				satVars, err := olm.PackageUniqueness().GetVariables(ctx, mockQuerier)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(satVars).Should(HaveLen(1))
+				Expect(satVars[0].Constraints()[0].String("pkg")).To(Equal("pkg permits at most 1 of certified-1.1-entity, certified-1.0-entity, community-1.2-entity"))
+			})
 		})
 	})
 })